@@ -0,0 +1,239 @@
+package aws
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	awsArn "github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+)
+
+const (
+	DefaultSessionName string = "aws-ami-manager"
+	DefaultRole        string = "terraform"
+	DefaultPartition   string = "aws"
+
+	minRoleDuration = 15 * time.Minute
+	maxRoleDuration = 12 * time.Hour
+
+	// maxChainedRoleDuration is the limit AWS sets on sessions of a role that is assumed with the credentials of a role
+	maxChainedRoleDuration = time.Hour
+)
+
+// AccountConfig configures how the role is assumed in an account. Empty fields fall back to the defaults.
+type AccountConfig struct {
+	// RoleArn is the full ARN of the role, instead of the role name given with --role
+	RoleArn string `json:"role_arn,omitempty"`
+	// Role is the name of the role, instead of the role name given with --role
+	Role        string `json:"role,omitempty"`
+	ExternalID  string `json:"external_id,omitempty"`
+	SessionName string `json:"session_name,omitempty"`
+	// Duration of the role session, e.g. 1h
+	Duration  string `json:"duration,omitempty"`
+	Partition string `json:"partition,omitempty"`
+	// Via are the ARN's of the roles that are assumed first, in order, e.g. a role in a hub account
+	Via []string `json:"via,omitempty"`
+	// CredentialsFile is a file with STS credentials for the account, which are used instead of assuming a role
+	CredentialsFile string `json:"credentials_file,omitempty"`
+
+	credentials *CredentialsProvider
+}
+
+// AccountsConfig holds the defaults and the overrides per account ID for assuming roles, e.g.
+//
+//	{
+//	  "defaults": {"session_name": "ami-pipeline", "duration": "1h", "via": ["arn:aws:iam::111111111111:role/hub"]},
+//	  "accounts": {
+//	    "123456789012": {"role_arn": "arn:aws:iam::123456789012:role/vendor/ami-access", "external_id": "abc123"}
+//	  }
+//	}
+type AccountsConfig struct {
+	Defaults AccountConfig            `json:"defaults"`
+	Accounts map[string]AccountConfig `json:"accounts"`
+}
+
+// LoadAccountsConfig reads and validates an accounts configuration file
+func LoadAccountsConfig(path string) (*AccountsConfig, error) {
+	content, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	accountsConfig := &AccountsConfig{}
+
+	err = json.Unmarshal(content, accountsConfig)
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse accounts configuration %s: %w", path, err)
+	}
+
+	err = accountsConfig.Defaults.validate("")
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid defaults in %s: %w", path, err)
+	}
+
+	if accountsConfig.Defaults.CredentialsFile != "" {
+		return nil, fmt.Errorf("invalid defaults in %s: credentials_file can only be set per account", path)
+	}
+
+	for account, accountConfig := range accountsConfig.Accounts {
+		if !isAccountID(account) {
+			return nil, fmt.Errorf("invalid account ID %s in %s", account, path)
+		}
+
+		err = accountConfig.validate(account)
+
+		if err == nil {
+			// the via and duration can each come from the defaults
+			err = accountsConfig.forAccount(account, "", "").validateChainedDuration()
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid configuration for account %s in %s: %w", account, path, err)
+		}
+
+		if accountConfig.CredentialsFile != "" {
+			accountConfig.credentials, err = LoadCredentialsProvider(accountConfig.CredentialsFile)
+
+			if err != nil {
+				return nil, fmt.Errorf("invalid configuration for account %s in %s: %w", account, path, err)
+			}
+
+			accountsConfig.Accounts[account] = accountConfig
+		}
+	}
+
+	return accountsConfig, nil
+}
+
+func (accountConfig AccountConfig) validate(account string) error {
+	// the credentials are used as they are, instead of assuming a role
+	if accountConfig.CredentialsFile != "" && (accountConfig.RoleArn != "" || accountConfig.Role != "" || len(accountConfig.Via) > 0 || accountConfig.ExternalID != "") {
+		return errors.New("credentials_file can't be combined with role_arn, role, via or external_id")
+	}
+
+	if accountConfig.RoleArn != "" {
+		parsed, err := awsArn.Parse(accountConfig.RoleArn)
+
+		if err != nil {
+			return fmt.Errorf("invalid role ARN %s: %w", accountConfig.RoleArn, err)
+		}
+
+		if account != "" && parsed.AccountID != account {
+			return fmt.Errorf("role %s is not in account %s", accountConfig.RoleArn, account)
+		}
+
+		if accountConfig.Partition != "" && parsed.Partition != accountConfig.Partition {
+			return fmt.Errorf("role %s is not in partition %s", accountConfig.RoleArn, accountConfig.Partition)
+		}
+	}
+
+	for _, roleArn := range accountConfig.Via {
+		if _, err := awsArn.Parse(roleArn); err != nil {
+			return fmt.Errorf("invalid role ARN %s in via: %w", roleArn, err)
+		}
+	}
+
+	switch accountConfig.Partition {
+	case "", PartitionAWS, PartitionAWSUSGov, PartitionAWSCN:
+	default:
+		return fmt.Errorf("unsupported partition %s", accountConfig.Partition)
+	}
+
+	if accountConfig.Duration != "" {
+		duration, err := time.ParseDuration(accountConfig.Duration)
+
+		if err != nil {
+			return fmt.Errorf("invalid duration %s: %w", accountConfig.Duration, err)
+		}
+
+		if duration < minRoleDuration || duration > maxRoleDuration {
+			return fmt.Errorf("duration %s must be between %s and %s", accountConfig.Duration, minRoleDuration, maxRoleDuration)
+		}
+	}
+
+	return accountConfig.validateChainedDuration()
+}
+
+// validateChainedDuration checks the duration of a role that is assumed via other roles against the limit of AWS
+func (accountConfig AccountConfig) validateChainedDuration() error {
+	if len(accountConfig.Via) == 0 || accountConfig.Duration == "" {
+		return nil
+	}
+
+	duration, err := time.ParseDuration(accountConfig.Duration)
+
+	if err != nil {
+		return fmt.Errorf("invalid duration %s: %w", accountConfig.Duration, err)
+	}
+
+	if duration > maxChainedRoleDuration {
+		return fmt.Errorf("duration %s must be at most %s when the role is assumed via other roles", accountConfig.Duration, maxChainedRoleDuration)
+	}
+
+	return nil
+}
+
+// forAccount returns the configuration of an account, with the empty fields set from the defaults, the role name and
+// the partition of the default account
+func (accountsConfig *AccountsConfig) forAccount(account string, role string, partition string) AccountConfig {
+	accountConfig := AccountConfig{}
+	defaults := AccountConfig{}
+
+	if accountsConfig != nil {
+		accountConfig = accountsConfig.Accounts[account]
+		defaults = accountsConfig.Defaults
+	}
+
+	// the role given on the command line takes precedence over the defaults, but not over the role of the account
+	accountConfig.Role = firstNonEmpty(accountConfig.Role, role, defaults.Role, DefaultRole)
+	accountConfig.ExternalID = firstNonEmpty(accountConfig.ExternalID, defaults.ExternalID)
+	accountConfig.SessionName = firstNonEmpty(accountConfig.SessionName, defaults.SessionName, DefaultSessionName)
+	accountConfig.Duration = firstNonEmpty(accountConfig.Duration, defaults.Duration)
+	accountConfig.Partition = firstNonEmpty(accountConfig.Partition, defaults.Partition, partition, DefaultPartition)
+
+	if accountConfig.Via == nil {
+		accountConfig.Via = defaults.Via
+	}
+
+	return accountConfig
+}
+
+// roleArnFor returns the ARN of the role to assume in the account, in the partition of the account, e.g.
+// arn:aws-us-gov:iam::123456789012:role/terraform in GovCloud
+func (accountConfig AccountConfig) roleArnFor(account string) string {
+	if accountConfig.RoleArn != "" {
+		return accountConfig.RoleArn
+	}
+
+	return "arn:" + accountConfig.Partition + ":iam::" + account + ":role/" + accountConfig.Role
+}
+
+// assumeRoleOptions sets the external ID, session name and duration of the role session
+func (accountConfig AccountConfig) assumeRoleOptions(options *stscreds.AssumeRoleOptions) {
+	if accountConfig.ExternalID != "" {
+		options.ExternalID = &accountConfig.ExternalID
+	}
+
+	options.RoleSessionName = accountConfig.SessionName
+
+	// the duration is validated when the configuration is loaded
+	if duration, err := time.ParseDuration(accountConfig.Duration); err == nil {
+		options.Duration = duration
+	}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,275 @@
+package aws
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeAccountsConfig writes an accounts configuration to a temporary file and returns its path
+func writeAccountsConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "accounts.json")
+
+	err := os.WriteFile(path, []byte(content), 0o600)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLoadAccountsConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{
+			name:    "empty",
+			content: `{}`,
+		},
+		{
+			name: "defaults and overrides",
+			content: `{
+				"defaults": {"role": "ami-access", "session_name": "ami-pipeline", "duration": "1h"},
+				"accounts": {
+					"123456789012": {"role_arn": "arn:aws:iam::123456789012:role/vendor/ami-access", "external_id": "abc123"},
+					"210987654321": {"role": "other", "duration": "12h"}
+				}
+			}`,
+		},
+		{
+			name:    "invalid JSON",
+			content: `{"accounts": `,
+			wantErr: true,
+		},
+		{
+			name:    "invalid account ID",
+			content: `{"accounts": {"12345": {"role": "ami-access"}}}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid role ARN",
+			content: `{"accounts": {"123456789012": {"role_arn": "ami-access"}}}`,
+			wantErr: true,
+		},
+		{
+			name:    "role ARN in another account",
+			content: `{"accounts": {"123456789012": {"role_arn": "arn:aws:iam::210987654321:role/ami-access"}}}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid duration",
+			content: `{"defaults": {"duration": "one hour"}}`,
+			wantErr: true,
+		},
+		{
+			name:    "duration too short",
+			content: `{"accounts": {"123456789012": {"duration": "10m"}}}`,
+			wantErr: true,
+		},
+		{
+			name:    "duration too long",
+			content: `{"defaults": {"duration": "13h"}}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadAccountsConfig(writeAccountsConfig(t, tt.content))
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LoadAccountsConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadAccountsConfigMissingFile(t *testing.T) {
+	_, err := LoadAccountsConfig(filepath.Join(t.TempDir(), "missing.json"))
+
+	if err == nil {
+		t.Error("LoadAccountsConfig() error = nil, want an error for a missing file")
+	}
+}
+
+func TestRoleArnFor(t *testing.T) {
+	accountsConfig := &AccountsConfig{
+		Defaults: AccountConfig{Role: "ami-access"},
+		Accounts: map[string]AccountConfig{
+			"123456789012": {RoleArn: "arn:aws:iam::123456789012:role/vendor/ami-access"},
+			"210987654321": {Role: "other"},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		accountsConfig *AccountsConfig
+		account        string
+		role           string
+		want           string
+	}{
+		{
+			name:    "role given as flag",
+			account: "123456789012",
+			role:    "terraform",
+			want:    "arn:aws:iam::123456789012:role/terraform",
+		},
+		{
+			name:           "role ARN override",
+			accountsConfig: accountsConfig,
+			account:        "123456789012",
+			role:           "terraform",
+			want:           "arn:aws:iam::123456789012:role/vendor/ami-access",
+		},
+		{
+			name:           "role override",
+			accountsConfig: accountsConfig,
+			account:        "210987654321",
+			role:           "terraform",
+			want:           "arn:aws:iam::210987654321:role/other",
+		},
+		{
+			name:           "role from the defaults",
+			accountsConfig: accountsConfig,
+			account:        "111122223333",
+			want:           "arn:aws:iam::111122223333:role/ami-access",
+		},
+		{
+			name:           "role given as flag over the defaults",
+			accountsConfig: accountsConfig,
+			account:        "111122223333",
+			role:           "pipeline",
+			want:           "arn:aws:iam::111122223333:role/pipeline",
+		},
+		{
+			name:    "default role",
+			account: "111122223333",
+			want:    "arn:aws:iam::111122223333:role/terraform",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.accountsConfig.forAccount(tt.account, tt.role, "").roleArnFor(tt.account)
+
+			if got != tt.want {
+				t.Errorf("roleArnFor() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadAccountsConfigVia(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{
+			name:    "via in the defaults",
+			content: `{"defaults": {"via": ["arn:aws:iam::111111111111:role/ami-hub"], "duration": "1h"}}`,
+		},
+		{
+			name:    "via per account",
+			content: `{"accounts": {"123456789012": {"via": ["arn:aws:iam::111111111111:role/ami-hub", "arn:aws:iam::222222222222:role/ami-spoke"]}}}`,
+		},
+		{
+			name:    "long duration without via",
+			content: `{"accounts": {"123456789012": {"duration": "12h"}}}`,
+		},
+		{
+			name:    "invalid via",
+			content: `{"defaults": {"via": ["ami-hub"]}}`,
+			wantErr: true,
+		},
+		{
+			name:    "duration above an hour with via",
+			content: `{"defaults": {"via": ["arn:aws:iam::111111111111:role/ami-hub"], "duration": "2h"}}`,
+			wantErr: true,
+		},
+		{
+			name:    "duration from the defaults and via from the account",
+			content: `{"defaults": {"duration": "2h"}, "accounts": {"123456789012": {"via": ["arn:aws:iam::111111111111:role/ami-hub"]}}}`,
+			wantErr: true,
+		},
+		{
+			name:    "via from the defaults and duration from the account",
+			content: `{"defaults": {"via": ["arn:aws:iam::111111111111:role/ami-hub"]}, "accounts": {"123456789012": {"duration": "90m"}}}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadAccountsConfig(writeAccountsConfig(t, tt.content))
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LoadAccountsConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadAccountsConfigCredentialsFile(t *testing.T) {
+	credentials := writeAccountsConfig(t, `{"AccessKeyId": "ASIAEXAMPLE", "SecretAccessKey": "secret", "SessionToken": "token", "Expiration": "2999-01-01T00:00:00Z"}`)
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{
+			name:    "credentials file",
+			content: `{"accounts": {"123456789012": {"credentials_file": "` + credentials + `"}}}`,
+		},
+		{
+			name:    "credentials file with the role from the defaults",
+			content: `{"defaults": {"role": "ami-access"}, "accounts": {"123456789012": {"credentials_file": "` + credentials + `"}}}`,
+		},
+		{
+			name:    "credentials file in the defaults",
+			content: `{"defaults": {"credentials_file": "` + credentials + `"}}`,
+			wantErr: true,
+		},
+		{
+			name:    "missing credentials file",
+			content: `{"accounts": {"123456789012": {"credentials_file": "` + credentials + `.missing"}}}`,
+			wantErr: true,
+		},
+		{
+			name:    "with role ARN",
+			content: `{"accounts": {"123456789012": {"credentials_file": "` + credentials + `", "role_arn": "arn:aws:iam::123456789012:role/ami-access"}}}`,
+			wantErr: true,
+		},
+		{
+			name:    "with role",
+			content: `{"accounts": {"123456789012": {"credentials_file": "` + credentials + `", "role": "ami-access"}}}`,
+			wantErr: true,
+		},
+		{
+			name:    "with via",
+			content: `{"accounts": {"123456789012": {"credentials_file": "` + credentials + `", "via": ["arn:aws:iam::111111111111:role/ami-hub"]}}}`,
+			wantErr: true,
+		},
+		{
+			name:    "with external ID",
+			content: `{"accounts": {"123456789012": {"credentials_file": "` + credentials + `", "external_id": "abc123"}}}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadAccountsConfig(writeAccountsConfig(t, tt.content))
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LoadAccountsConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -17,16 +17,20 @@ import (
 var (
 	ConfigManager *ConfigurationManager
 	ec2Services   = make(map[string]map[string]*ec2.Client)
+	ec2ServicesMu sync.Mutex
 )
 
 func getEC2ServiceForAccountAndRegion(account string, region string) *ec2.Client {
 	log.Debugf("getEC2ServiceForAccountAndRegion: account %s, region %s", account, region)
+	ec2ServicesMu.Lock()
+	defer ec2ServicesMu.Unlock()
+
 	if ec2Services[account] == nil {
 		ec2Services[account] = make(map[string]*ec2.Client)
 	}
 
 	if ec2Services[account][region] == nil {
-		ec2Services[account][region] = ec2.NewFromConfig(ConfigManager.getConfigurationForAccountAndRegion(account, region))
+		ec2Services[account][region] = ConfigManager.newEC2Client(ConfigManager.getConfigurationForAccountAndRegion(account, region))
 	}
 	return ec2Services[account][region]
 }
@@ -35,10 +39,26 @@ type Ami struct {
 	SourceAmiID   string
 	SourceRegion  string
 	SourceAmiName string
+	// SourceAccount is the account that owns the source AMI, when it is shared by another account. Its metadata and
+	// tags are read in that account, because the tags of a shared AMI are not visible to the accounts it is shared with.
+	SourceAccount string
 	SourceAmiTags *[]ec2Types.Tag
 	AWSImage      *ec2Types.Image
+	Description   string
 
 	AmisPerRegion map[string]*Ami
+
+	// TagRules transform the source tags that are set on the copies
+	TagRules *TagRules
+	// Naming renders the names and descriptions of the copies
+	Naming *Naming
+	// SSMPublisher publishes the ID's of the copies once they are all available
+	SSMPublisher *SSMPublisher
+	// DeepCopy copies the AMI into the target accounts, so they own independent copies
+	DeepCopy *DeepCopy
+	// CopiesPerAccount are the ID's of the deep copies per account
+	CopiesPerAccount map[string]string
+	CopiedAt         time.Time
 }
 
 func NewAmi(sourceAmiID string) *Ami {
@@ -59,7 +79,12 @@ func NewAmiWithRegions(sourceAmiID string, sourceRegion string, regions []string
 
 func (ami *Ami) fetchMetadata() error {
 	log.Debug("Fetching metadata about the AMI")
-	ec2svc := getEC2ServiceForAccountAndRegion(*ConfigManager.defaultAccountID, ami.SourceRegion)
+	account := *ConfigManager.defaultAccountID
+	if ami.SourceAccount != "" {
+		account = ami.SourceAccount
+	}
+
+	ec2svc := getEC2ServiceForAccountAndRegion(account, ami.SourceRegion)
 
 	var amiList []string
 	amiList = append(amiList, ami.SourceAmiID)
@@ -90,12 +115,31 @@ func (ami *Ami) fetchMetadata() error {
 
 	if ami.SourceAmiTags == nil && images[0].Tags != nil {
 		ami.SourceAmiTags = &images[0].Tags
-		log.Debugf("AMI tags: %s", ami.SourceAmiTags)
+		log.Debugf("AMI tags: %v", *ami.SourceAmiTags)
 	}
 
 	return nil
 }
 
+// ownerAccount returns the account that owns the source AMI
+func (ami *Ami) ownerAccount() string {
+	if ami.AWSImage != nil && ami.AWSImage.OwnerId != nil {
+		return *ami.AWSImage.OwnerId
+	}
+
+	if ami.SourceAccount != "" {
+		return ami.SourceAccount
+	}
+
+	return *ConfigManager.defaultAccountID
+}
+
+// isShared returns true when the source AMI is owned by another account than the default account. A shared AMI is
+// copied to its own region as well, so the default account owns all the copies.
+func (ami *Ami) isShared() bool {
+	return ami.ownerAccount() != *ConfigManager.defaultAccountID
+}
+
 func (ami *Ami) Copy() {
 	// Fetch name and tags for the source AMI
 	err := ami.fetchMetadata()
@@ -104,6 +148,32 @@ func (ami *Ami) Copy() {
 		log.Fatal(err)
 	}
 
+	if ami.SourceAccount != "" && ami.ownerAccount() != ami.SourceAccount {
+		log.Fatalf("AMI %s is owned by account %s, not by source account %s", ami.SourceAmiID, ami.ownerAccount(), ami.SourceAccount)
+	}
+
+	if ami.isShared() && ami.SourceAccount == "" {
+		log.Warnf("AMI %s is shared by account %s, its tags are only copied with --source-account", ami.SourceAmiID, ami.ownerAccount())
+	}
+
+	ami.CopiedAt = time.Now().UTC()
+
+	// render and validate the names before anything is copied
+	for region, relatedAmi := range ami.AmisPerRegion {
+		if region == ami.SourceRegion && !ami.isShared() {
+			continue
+		}
+
+		name, description, err := ami.nameAndDescriptionFor(region)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		relatedAmi.SourceAmiName = name
+		relatedAmi.Description = description
+	}
+
 	var wg sync.WaitGroup
 
 	// in this loop region is the key
@@ -117,8 +187,9 @@ func (ami *Ami) Copy() {
 				err        error
 			)
 
-			// We obviously don't have to copy the AMI to a region where it already exists
-			if region != amiF.SourceRegion {
+			// We obviously don't have to copy the AMI to a region where it already exists, unless it is owned by
+			// another account
+			if region != amiF.SourceRegion || amiF.isShared() {
 				log.Debug("Starting copying")
 
 				relatedAmi, err = amiF.copyToRegion(region)
@@ -136,10 +207,31 @@ func (ami *Ami) Copy() {
 				relatedAmi = amiF
 			}
 
-			for _, account := range ConfigManager.getAccounts() {
+			tagAccounts, err := ConfigManager.withCredentials(ConfigManager.getAccounts())
+
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			for _, account := range tagAccounts {
 				// the original AMI already has the tags
 				if account != *ConfigManager.defaultAccountID {
-					err := relatedAmi.setTagsForAccount(account, *amiF.SourceAmiTags)
+					var tags []ec2Types.Tag
+					if relatedAmi == amiF {
+						tags, err = amiF.tagsForSource(account)
+					} else {
+						tags, err = amiF.tagsFor(region, account)
+					}
+
+					if err != nil {
+						log.Fatal(err)
+					}
+
+					if len(tags) == 0 {
+						continue
+					}
+
+					err = relatedAmi.setTagsForAccount(account, tags)
 
 					if err != nil {
 						log.Fatal(err)
@@ -147,21 +239,73 @@ func (ami *Ami) Copy() {
 				}
 			}
 
+			if amiF.DeepCopy != nil {
+				relatedAmi.CopiesPerAccount, err = amiF.DeepCopy.copyToAccounts(amiF, region, relatedAmi)
+
+				if err != nil {
+					log.Fatal(err)
+				}
+			}
+
 			wg.Done()
 		}(ami, region)
 	}
 
 	wg.Wait()
+
+	if ami.SSMPublisher != nil {
+		for region, relatedAmi := range ami.AmisPerRegion {
+			amiID := relatedAmi.SourceAmiID
+			if region == ami.SourceRegion && !ami.isShared() {
+				amiID = ami.SourceAmiID
+				relatedAmi = ami
+			}
+
+			err := ami.SSMPublisher.publish(ami, region, amiID, relatedAmi.CopiesPerAccount)
+
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
 }
 
 func (ami *Ami) copyToRegion(region string) (*Ami, error) {
 	relatedAmi := ami.AmisPerRegion[region]
 
+	tags, err := ami.tagsFor(region, *ConfigManager.defaultAccountID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	tags = append(tags, ami.lineageTags()...)
+
+	// let EC2 copy the tags when they are copied as is, only the added tags have to be set then. The tags of a shared
+	// AMI are not visible to EC2 in this account, so they are always set.
+	copyImageTags := ami.TagRules.keepsSourceTags() && !ami.isShared()
+	imageTags := tags
+	if copyImageTags {
+		imageTags = ami.withoutSourceTags(tags)
+	}
+
 	log.Infof("Copying AMI to region %s", relatedAmi.SourceRegion)
 	copyImageInput := &ec2.CopyImageInput{
-		Name:          aws.String(ami.SourceAmiName),
+		Name:          aws.String(relatedAmi.SourceAmiName),
+		Description:   optionalString(relatedAmi.Description),
 		SourceRegion:  aws.String(ami.SourceRegion),
 		SourceImageId: aws.String(ami.SourceAmiID),
+		CopyImageTags: aws.Bool(copyImageTags),
+		TagSpecifications: []ec2Types.TagSpecification{
+			{
+				ResourceType: ec2Types.ResourceTypeImage,
+				Tags:         imageTags,
+			},
+			{
+				ResourceType: ec2Types.ResourceTypeSnapshot,
+				Tags:         tags,
+			},
+		},
 	}
 	ec2Service := getEC2ServiceForAccountAndRegion(*ConfigManager.defaultAccountID, relatedAmi.SourceRegion)
 
@@ -174,26 +318,49 @@ func (ami *Ami) copyToRegion(region string) (*Ami, error) {
 	log.Infof("New AMI ID: %s", *output.ImageId)
 	relatedAmi.SourceAmiID = *output.ImageId
 
-	// Wait until AMI is `available`
+	err = waitUntilAvailable(ec2Service, relatedAmi.SourceAmiID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = relatedAmi.fetchMetadata()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return relatedAmi, nil
+}
+
+// waitUntilAvailable polls the state of an image until it is available
+func waitUntilAvailable(ec2Service *ec2.Client, imageID string) error {
 	duration, _ := time.ParseDuration("5s")
 	start := time.Now()
 
 	for {
-		_ = relatedAmi.fetchMetadata()
+		result, err := ec2Service.DescribeImages(context.Background(), &ec2.DescribeImagesInput{
+			ImageIds: []string{imageID},
+		})
 
-		if relatedAmi.isAvailable() == true {
-			log.Infof("AMI %s is available.", relatedAmi.SourceAmiID)
-			break
+		// a new image can't always be described right away
+		if err != nil && !isInvalidAmiID(err) {
+			return err
 		}
 
-		log.Infof("AMI %s is not available yet. Waiting %f seconds.", relatedAmi.SourceAmiID, duration.Seconds())
+		if err == nil && len(result.Images) > 0 {
+			switch result.Images[0].State {
+			case ec2Types.ImageStateAvailable:
+				log.Infof("AMI %s took %s to become available", imageID, time.Since(start))
+				return nil
+			case ec2Types.ImageStateFailed, ec2Types.ImageStateError:
+				return fmt.Errorf("AMI %s is in state %s", imageID, result.Images[0].State)
+			}
+		}
+
+		log.Infof("AMI %s is not available yet. Waiting %f seconds.", imageID, duration.Seconds())
 		time.Sleep(duration)
 	}
-
-	elapsed := time.Since(start)
-	log.Infof("AMI took %s to become available", elapsed)
-
-	return relatedAmi, nil
 }
 
 func (ami *Ami) setOwners(owners []string) error {
@@ -215,15 +382,6 @@ func (ami *Ami) setOwners(owners []string) error {
 	return err
 }
 
-func (ami *Ami) isAvailable() bool {
-	if ami.AWSImage == nil {
-		_ = ami.fetchMetadata()
-	}
-
-	log.Debugf("Current AMI state is %s", ami.AWSImage.State)
-	return ami.AWSImage.State == ec2Types.ImageStateAvailable
-}
-
 func (ami *Ami) setTagsForAccount(account string, tags []ec2Types.Tag) error {
 	log.Infof("Setting tags for account %s", account)
 	log.Debug(ami)
@@ -251,7 +409,7 @@ func convertRegionSliceToAmi(slice []string) map[string]*Ami {
 }
 
 func createLaunchPermissionsForOwners(owners []string) []ec2Types.LaunchPermission {
-	launchPermissions := make([]ec2Types.LaunchPermission, len(owners))
+	launchPermissions := make([]ec2Types.LaunchPermission, 0, len(owners))
 	for _, owner := range owners {
 		launchPermissions = append(launchPermissions, ec2Types.LaunchPermission{
 			UserId: aws.String(owner),
@@ -260,12 +418,31 @@ func createLaunchPermissionsForOwners(owners []string) []ec2Types.LaunchPermissi
 	return launchPermissions
 }
 
+// CleanupPlan holds the AMI's per region that will be removed by a cleanup, and the tags the accounts have set on them
+type CleanupPlan struct {
+	ImagesPerRegion map[string][]ec2Types.Image
+	// tagsPerImage are the tags of the images per account, by image ID. They are described before anything is removed,
+	// so a failure leaves every image in place.
+	tagsPerImage map[string]map[string][]ec2Types.Tag
+}
+
 func (ami *Ami) Cleanup(regions []string, tagsToMatch []string, versionsToKeep int) error {
+	plan, err := ami.PlanCleanup(regions, tagsToMatch, versionsToKeep)
+
+	if err != nil {
+		return err
+	}
+
+	return plan.Execute()
+}
+
+// PlanCleanup determines which AMI's a cleanup removes, without removing them. Protected AMI's are left out.
+func (ami *Ami) PlanCleanup(regions []string, tagsToMatch []string, versionsToKeep int) (*CleanupPlan, error) {
 	// describe ami
 	err := ami.fetchMetadata()
 
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	// convert Tag slice to map for easier lookup
@@ -279,90 +456,436 @@ func (ami *Ami) Cleanup(regions []string, tagsToMatch []string, versionsToKeep i
 		}
 	}
 
+	plan := &CleanupPlan{
+		ImagesPerRegion: make(map[string][]ec2Types.Image),
+		tagsPerImage:    make(map[string]map[string][]ec2Types.Tag),
+	}
+
 	for _, region := range regions {
 		ec2svc := getEC2ServiceForAccountAndRegion(*ConfigManager.defaultAccountID, region)
 
-		describeImagesInput := ec2.DescribeImagesInput{
-			Filters: convertTagSliceToFilter(matchedTags),
+		// only our own AMI's can be cleaned up
+		images, err := describeOwnImages(ec2svc, convertTagSliceToFilter(matchedTags))
+
+		if err != nil {
+			return nil, err
 		}
-		result, err := ec2svc.DescribeImages(context.Background(), &describeImagesInput)
+
+		err = sortImagesByCreationDate(images)
 
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
 		}
 
-		images := result.Images
+		// keep the first (i.e. most recent) AMI's
+		if len(images) <= versionsToKeep {
+			continue
+		}
 
-		// sort the returned images
-		sort.Slice(images, func(i, j int) bool {
-			firstDate, err := time.Parse(time.RFC3339, *images[i].CreationDate)
+		for i := versionsToKeep; i < len(images); i++ {
+			image := images[i]
 
-			if err != nil {
-				log.Fatal(err)
+			if err := Protection.verify(&image); err != nil {
+				log.Warnf("Skipping AMI in region %s: %s", region, err)
+				continue
 			}
 
-			secondDate, err := time.Parse(time.RFC3339, *images[j].CreationDate)
+			tagsPerAccount, err := describeTagsInAccounts(*image.ImageId, region)
 
 			if err != nil {
-				log.Fatal(err)
+				return nil, err
 			}
 
-			return firstDate.After(secondDate)
-		})
+			plan.ImagesPerRegion[region] = append(plan.ImagesPerRegion[region], image)
+			plan.tagsPerImage[*image.ImageId] = tagsPerAccount
+		}
+	}
+
+	return plan, nil
+}
+
+// IsEmpty returns true when the cleanup has nothing to remove
+func (plan *CleanupPlan) IsEmpty() bool {
+	for _, images := range plan.ImagesPerRegion {
+		if len(images) > 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Describe returns a tab-separated line with the region, ID, name and creation date of every AMI in the plan
+func (plan *CleanupPlan) Describe() []string {
+	var lines []string
+
+	for region, images := range plan.ImagesPerRegion {
+		for _, image := range images {
+			lines = append(lines, describeImage(region, &image))
+		}
+	}
+
+	sort.Strings(lines)
+
+	return lines
+}
+
+// Execute removes the AMI's in the plan, after removing the tags the accounts have set on them and revoking their
+// launch permissions
+func (plan *CleanupPlan) Execute() error {
+	for region, images := range plan.ImagesPerRegion {
+		ec2svc := getEC2ServiceForAccountAndRegion(*ConfigManager.defaultAccountID, region)
 
-		var i = 0
 		for _, image := range images {
-			// keep the first (i.e. most recent) AMI
-			if i >= versionsToKeep {
-				log.Debugf("Deleting image %s", *image.ImageId)
-				err = removeAwsAmi(&image, ec2svc)
-				log.Infof("Image %s deleted", *image.ImageId)
+			log.Debugf("Deleting image %s", *image.ImageId)
+
+			for _, account := range sortedKeys(plan.tagsPerImage[*image.ImageId]) {
+				err := removeTagsForAccount(account, region, *image.ImageId, plan.tagsPerImage[*image.ImageId][account])
 
 				if err != nil {
-					log.Fatal(err)
+					return fmt.Errorf("account %s: %w", account, err)
 				}
 			}
-			i++
-		}
 
+			err := revokeLaunchPermissions(*image.ImageId, ec2svc)
+
+			if err != nil {
+				return err
+			}
+
+			err = removeAwsAmi(&image, ec2svc)
+
+			if err != nil {
+				return err
+			}
+
+			log.Infof("Image %s deleted", *image.ImageId)
+		}
 	}
+
+	return nil
+}
+
+// sortImagesByCreationDate sorts the images with the most recent image first
+func sortImagesByCreationDate(images []ec2Types.Image) error {
+	var err error
+
+	sort.SliceStable(images, func(i, j int) bool {
+		firstDate, parseErr := time.Parse(time.RFC3339, aws.ToString(images[i].CreationDate))
+
+		if parseErr != nil {
+			err = parseErr
+		}
+
+		secondDate, parseErr := time.Parse(time.RFC3339, aws.ToString(images[j].CreationDate))
+
+		if parseErr != nil {
+			err = parseErr
+		}
+
+		return firstDate.After(secondDate)
+	})
+
 	return err
 }
 
+// describeImage returns a tab-separated line with the region, ID, name and creation date of the image
+func describeImage(region string, image *ec2Types.Image) string {
+	return fmt.Sprintf("%s\t%s\t%s\t%s", region, aws.ToString(image.ImageId), aws.ToString(image.Name), aws.ToString(image.CreationDate))
+}
+
 func (ami *Ami) RemoveAmi() error {
 	// describe ami
 	err := ami.fetchMetadata()
 
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
-	ec2Service := getEC2ServiceForAccountAndRegion(*ConfigManager.defaultAccountID, ConfigManager.GetDefaultRegion())
-	err = removeAwsAmi(ami.AWSImage, ec2Service)
+	ec2Service := getEC2ServiceForAccountAndRegion(*ConfigManager.defaultAccountID, ami.SourceRegion)
+
+	return removeAwsAmi(ami.AWSImage, ec2Service)
+}
+
+// RemoveAllCopies is the inverse of Copy. It removes the deep copies in the other accounts, every regional copy of the
+// AMI and the AMI itself, after taking away the launch permissions and the tags that were set in the other accounts. A
+// shared AMI is owned by another account, so only its copies are removed, including the copy in its own region.
+func (ami *Ami) RemoveAllCopies(regions []string) error {
+	err := ami.fetchMetadata()
 
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+
+	for _, region := range regions {
+		wg.Add(1)
+		go func(region string) {
+			defer wg.Done()
+
+			err := ami.removeDeepCopiesInRegion(region)
+
+			// the source AMI is removed last
+			if err == nil && (region != ami.SourceRegion || ami.isShared()) {
+				err = ami.removeCopiesInRegion(region)
+			}
+
+			if err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("region %s: %w", region, err))
+				mu.Unlock()
+			}
+		}(region)
+	}
+
+	wg.Wait()
+
+	// keep the source AMI when a copy could not be removed, so the copies can still be found by a next run
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	if ami.isShared() {
+		return nil
+	}
+
+	return ami.removeImageFromAllAccounts(ami.AWSImage, ami.SourceRegion)
+}
+
+func (ami *Ami) removeCopiesInRegion(region string) error {
+	images, err := ami.findCopies(region)
+
+	if err != nil {
+		return err
+	}
+
+	if len(images) == 0 {
+		log.Infof("No copies of AMI %s found in region %s", ami.SourceAmiID, region)
+		return nil
+	}
+
+	for i := range images {
+		err = ami.removeImageFromAllAccounts(&images[i], region)
+
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+// removeDeepCopiesInRegion removes the deep copies that the other accounts own in a region
+func (ami *Ami) removeDeepCopiesInRegion(region string) error {
+	accounts, err := ConfigManager.withCredentials(ConfigManager.getAccounts())
+
+	if err != nil {
+		return err
+	}
+
+	for _, account := range accounts {
+		if account == *ConfigManager.defaultAccountID {
+			continue
+		}
+
+		images, err := ami.findDeepCopies(account, region)
+
+		if err != nil {
+			return fmt.Errorf("account %s: %w", account, err)
+		}
+
+		ec2Service := getEC2ServiceForAccountAndRegion(account, region)
+
+		for i := range images {
+			log.Infof("Removing deep copy %s in account %s, region %s", *images[i].ImageId, account, region)
+
+			err = removeAwsAmi(&images[i], ec2Service)
+
+			if err != nil {
+				return fmt.Errorf("account %s: %w", account, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// removeImageFromAllAccounts undoes what Copy did for a single image: the tags in the target accounts are removed
+// first, as the image is no longer visible to those accounts once the launch permissions are revoked.
+func (ami *Ami) removeImageFromAllAccounts(image *ec2Types.Image, region string) error {
+	log.Infof("Removing AMI %s in region %s", *image.ImageId, region)
+
+	// check the protection before anything is changed, so a protected image stays usable
+	err := Protection.verify(image)
+
+	if err != nil {
+		return err
+	}
+
+	if ami.SourceAmiTags != nil && len(*ami.SourceAmiTags) > 0 {
+		accounts, err := ConfigManager.withCredentials(ConfigManager.getAccounts())
+
+		if err != nil {
+			return err
+		}
+
+		for _, account := range accounts {
+			// the tags in our own account are removed together with the image
+			if account == *ConfigManager.defaultAccountID {
+				continue
+			}
+
+			err := removeTagsForAccount(account, region, *image.ImageId, *ami.SourceAmiTags)
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	ec2Service := getEC2ServiceForAccountAndRegion(*ConfigManager.defaultAccountID, region)
+
+	err = revokeLaunchPermissions(*image.ImageId, ec2Service)
+
+	if err != nil {
+		return err
+	}
+
+	err = removeAwsAmi(image, ec2Service)
+
+	if err != nil {
+		return err
+	}
+
+	log.Infof("AMI %s in region %s has been removed", *image.ImageId, region)
+
+	return nil
+}
+
+// describeTagsInAccounts returns the tags that every account has set on an image, by account. An image that is not
+// shared with an account, e.g. an older version, has no tags in that account.
+func describeTagsInAccounts(imageID string, region string) (map[string][]ec2Types.Tag, error) {
+	accounts, err := ConfigManager.withCredentials(ConfigManager.getAccounts())
+
+	if err != nil {
+		return nil, err
+	}
+
+	tagsPerAccount := make(map[string][]ec2Types.Tag)
+
+	for _, account := range accounts {
+		if account == *ConfigManager.defaultAccountID {
+			continue
+		}
+
+		tags, err := describeTagsForAccount(account, region, imageID)
+
+		if isInvalidAmiID(err) {
+			log.Debugf("AMI %s in region %s is not visible to account %s", imageID, region, account)
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("account %s: %w", account, err)
+		}
+
+		if len(tags) > 0 {
+			tagsPerAccount[account] = tags
+		}
+	}
+
+	return tagsPerAccount, nil
+}
+
+func removeTagsForAccount(account string, region string, imageID string, tags []ec2Types.Tag) error {
+	log.Infof("Removing tags for account %s", account)
+	ec2service := getEC2ServiceForAccountAndRegion(account, region)
+
+	// only pass the keys, so the tags are removed regardless of their value
+	keys := make([]ec2Types.Tag, len(tags))
+	for i, tag := range tags {
+		keys[i] = ec2Types.Tag{Key: tag.Key}
+	}
+
+	input := &ec2.DeleteTagsInput{
+		Resources: []string{imageID},
+		Tags:      keys,
+	}
+
+	_, err := ec2service.DeleteTags(context.Background(), input)
+
+	return err
+}
+
+func revokeLaunchPermissions(imageID string, ec2Service *ec2.Client) error {
+	describeImageAttributeInput := &ec2.DescribeImageAttributeInput{
+		ImageId:   aws.String(imageID),
+		Attribute: ec2Types.ImageAttributeNameLaunchPermission,
+	}
+
+	attribute, err := ec2Service.DescribeImageAttribute(context.Background(), describeImageAttributeInput)
+
+	if err != nil {
+		return err
+	}
+
+	if len(attribute.LaunchPermissions) == 0 {
+		return nil
+	}
+
+	log.Debugf("Revoking %d launch permissions of AMI %s", len(attribute.LaunchPermissions), imageID)
+
+	modifyImageAttributeInput := &ec2.ModifyImageAttributeInput{
+		ImageId: aws.String(imageID),
+		LaunchPermission: &ec2Types.LaunchPermissionModifications{
+			Remove: attribute.LaunchPermissions,
+		},
+	}
+
+	_, err = ec2Service.ModifyImageAttribute(context.Background(), modifyImageAttributeInput)
+
+	return err
+}
+
 func removeAwsAmi(image *ec2Types.Image, ec2Service *ec2.Client) error {
+	err := Protection.verify(image)
+
+	if err != nil {
+		return err
+	}
+
+	// only reached for protected images when the removal is forced
+	err = disableDeregistrationProtection(image, ec2Service)
+
+	if err != nil {
+		return err
+	}
+
 	// deregister ami
 	deregisterAmiInput := &ec2.DeregisterImageInput{
 		ImageId: image.ImageId,
 	}
 
-	_, err := ec2Service.DeregisterImage(context.Background(), deregisterAmiInput)
+	_, err = ec2Service.DeregisterImage(context.Background(), deregisterAmiInput)
 
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	log.Debug("AMI is de-registered.")
 
 	// delete snapshot
 	for _, mapping := range image.BlockDeviceMappings {
+		// instance store volumes don't have a snapshot
+		if mapping.Ebs == nil || mapping.Ebs.SnapshotId == nil {
+			continue
+		}
+
 		deleteSnapshotInput := &ec2.DeleteSnapshotInput{
 			SnapshotId: mapping.Ebs.SnapshotId,
 		}
@@ -401,9 +924,7 @@ func convertTagSliceToFilter(tags []ec2Types.Tag) []ec2Types.Filter {
 
 func convertTagToFilter(tag ec2Types.Tag) ec2Types.Filter {
 	name := "tag:" + *tag.Key
-	values := make([]string, 1)
-
-	values = append(values, *tag.Value)
+	values := []string{*tag.Value}
 
 	return ec2Types.Filter{
 		Name:   &name,
@@ -0,0 +1,343 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultChannelTag string = "channel"
+)
+
+// Channel is a release channel, e.g. production, that is held by one AMI and its copies at a time. The channel is
+// marked with a tag on the AMI's in every account. The AMI that held the channel before is kept in the history tags.
+type Channel struct {
+	Name   string
+	TagKey string
+}
+
+func NewChannel(name string, tagKey string) *Channel {
+	return &Channel{
+		Name:   name,
+		TagKey: tagKey,
+	}
+}
+
+func (channel *Channel) previousTag() string {
+	return "ami-manager:" + channel.Name + ":previous"
+}
+
+func (channel *Channel) promotedAtTag() string {
+	return "ami-manager:" + channel.Name + ":promoted-at"
+}
+
+func (channel *Channel) successorTag() string {
+	return "ami-manager:" + channel.Name + ":successor"
+}
+
+// Promote moves the channel to the AMI and its copies in the regions. In every region, the channel tag is removed from
+// the AMI's that held the channel and set on the new AMI, in every account. When one of the changes fails, the changes
+// that were already made are undone.
+func (channel *Channel) Promote(ami *Ami, regions []string) error {
+	err := ami.fetchMetadata()
+
+	if err != nil {
+		return err
+	}
+
+	tx, err := newTagTransaction()
+
+	if err != nil {
+		return err
+	}
+
+	promotedAt := time.Now().UTC().Format(time.RFC3339)
+
+	for _, region := range regions {
+		var images []ec2Types.Image
+
+		if region == ami.SourceRegion {
+			images = []ec2Types.Image{*ami.AWSImage}
+		} else {
+			images, err = ami.findCopies(region)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(images) == 0 {
+			return fmt.Errorf("no copy of AMI %s found in region %s", ami.SourceAmiID, region)
+		}
+
+		holders, err := channel.findHolders(region)
+
+		if err != nil {
+			return err
+		}
+
+		for _, image := range images {
+			for _, holder := range holders {
+				if *holder.ImageId == *image.ImageId {
+					return fmt.Errorf("AMI %s already holds channel %s in region %s", *image.ImageId, channel.Name, region)
+				}
+			}
+
+			newTags := map[string]string{
+				channel.TagKey:          channel.Name,
+				channel.promotedAtTag(): promotedAt,
+			}
+
+			// the most recent holder is the one to roll back to
+			if len(holders) > 0 {
+				newTags[channel.previousTag()] = *holders[0].ImageId
+			}
+
+			tx.add(region, *image.ImageId, newTags, nil)
+		}
+
+		for _, holder := range holders {
+			tx.add(region, *holder.ImageId, map[string]string{channel.successorTag(): *images[0].ImageId}, []string{channel.TagKey})
+		}
+	}
+
+	return tx.commit()
+}
+
+// Rollback moves the channel back to the AMI's that held it before, in every region and account
+func (channel *Channel) Rollback(regions []string) error {
+	tx, err := newTagTransaction()
+
+	if err != nil {
+		return err
+	}
+
+	for _, region := range regions {
+		holders, err := channel.findHolders(region)
+
+		if err != nil {
+			return err
+		}
+
+		if len(holders) == 0 {
+			return fmt.Errorf("no AMI holds channel %s in region %s", channel.Name, region)
+		}
+
+		holder := holders[0]
+		previousID, ok := convertTagSliceToValueMap(holder.Tags)[channel.previousTag()]
+
+		if !ok || previousID == "" {
+			return fmt.Errorf("AMI %s in region %s has no previous AMI for channel %s", *holder.ImageId, region, channel.Name)
+		}
+
+		log.Infof("Rolling back channel %s in region %s from %s to %s", channel.Name, region, *holder.ImageId, previousID)
+
+		for _, holder := range holders {
+			tx.add(region, *holder.ImageId, nil, []string{channel.TagKey})
+		}
+
+		tx.add(region, previousID, map[string]string{channel.TagKey: channel.Name}, []string{channel.successorTag()})
+	}
+
+	return tx.commit()
+}
+
+// findHolders returns the AMI's in the region that hold the channel, the most recently promoted one first. Normally
+// there is at most one, but the channel tag can also have been set by hand.
+func (channel *Channel) findHolders(region string) ([]ec2Types.Image, error) {
+	ec2svc := getEC2ServiceForAccountAndRegion(*ConfigManager.defaultAccountID, region)
+
+	images, err := describeOwnImages(ec2svc, []ec2Types.Filter{
+		{
+			Name:   aws.String("tag:" + channel.TagKey),
+			Values: []string{channel.Name},
+		},
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(images) > 1 {
+		log.Warnf("%d AMI's hold channel %s in region %s, the channel is removed from all of them", len(images), channel.Name, region)
+
+		err = channel.sortHolders(images)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return images, nil
+}
+
+// sortHolders sorts the holders by the time they were promoted, the most recent one first. An AMI can be promoted again
+// after a newer one, so the creation date only orders the holders that were not promoted, e.g. tagged by hand, which
+// come last.
+func (channel *Channel) sortHolders(holders []ec2Types.Image) error {
+	err := sortImagesByCreationDate(holders)
+
+	if err != nil {
+		return err
+	}
+
+	promotedAt := func(image ec2Types.Image) string {
+		return convertTagSliceToValueMap(image.Tags)[channel.promotedAtTag()]
+	}
+
+	// the promoted-at tags are RFC 3339 timestamps in UTC, which sort as strings
+	sort.SliceStable(holders, func(i, j int) bool {
+		return promotedAt(holders[i]) > promotedAt(holders[j])
+	})
+
+	return nil
+}
+
+// tagChange sets and removes tags of an image in an account
+type tagChange struct {
+	account string
+	region  string
+	imageID string
+	set     map[string]string
+	unset   []string
+}
+
+// tagTransaction applies tag changes to images in every account, and undoes the applied changes when one fails
+type tagTransaction struct {
+	// accounts are the accounts the changes are applied in
+	accounts []string
+	changes  []tagChange
+}
+
+// newTagTransaction returns a transaction for the accounts whose credentials can be retrieved
+func newTagTransaction() (*tagTransaction, error) {
+	accounts, err := ConfigManager.withCredentials(ConfigManager.getAllAccounts())
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &tagTransaction{accounts: accounts}, nil
+}
+
+func (tx *tagTransaction) add(region string, imageID string, set map[string]string, unset []string) {
+	for _, account := range tx.accounts {
+		tx.changes = append(tx.changes, tagChange{
+			account: account,
+			region:  region,
+			imageID: imageID,
+			set:     set,
+			unset:   unset,
+		})
+	}
+}
+
+func (tx *tagTransaction) commit() error {
+	var undo []tagChange
+
+	for _, change := range tx.changes {
+		inverse, err := change.inverse()
+
+		if err == nil {
+			err = change.apply()
+		}
+
+		if err != nil {
+			err = fmt.Errorf("account %s, region %s, AMI %s: %w", change.account, change.region, change.imageID, err)
+			log.Errorf("Undoing %d changes: %s", len(undo), err)
+
+			// undo in reverse order
+			for i := len(undo) - 1; i >= 0; i-- {
+				if undoErr := undo[i].apply(); undoErr != nil {
+					err = errors.Join(err, fmt.Errorf("unable to undo the change to AMI %s in account %s: %w", undo[i].imageID, undo[i].account, undoErr))
+				}
+			}
+
+			return err
+		}
+
+		undo = append(undo, inverse)
+	}
+
+	return nil
+}
+
+// inverse returns the change that restores the current tags of the image
+func (change tagChange) inverse() (tagChange, error) {
+	tags, err := describeTagsForAccount(change.account, change.region, change.imageID)
+
+	if err != nil {
+		return tagChange{}, err
+	}
+
+	return change.inverseFor(convertTagSliceToValueMap(tags)), nil
+}
+
+// inverseFor returns the change that restores the current tags
+func (change tagChange) inverseFor(current map[string]string) tagChange {
+	inverse := tagChange{
+		account: change.account,
+		region:  change.region,
+		imageID: change.imageID,
+		set:     make(map[string]string),
+	}
+
+	for key := range change.set {
+		if value, ok := current[key]; ok {
+			inverse.set[key] = value
+		} else {
+			inverse.unset = append(inverse.unset, key)
+		}
+	}
+
+	for _, key := range change.unset {
+		if value, ok := current[key]; ok {
+			inverse.set[key] = value
+		}
+	}
+
+	return inverse
+}
+
+func (change tagChange) apply() error {
+	ec2svc := getEC2ServiceForAccountAndRegion(change.account, change.region)
+
+	if len(change.unset) > 0 {
+		keys := make([]ec2Types.Tag, len(change.unset))
+		for i, key := range change.unset {
+			keys[i] = ec2Types.Tag{Key: aws.String(key)}
+		}
+
+		_, err := ec2svc.DeleteTags(context.Background(), &ec2.DeleteTagsInput{
+			Resources: []string{change.imageID},
+			Tags:      keys,
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(change.set) > 0 {
+		_, err := ec2svc.CreateTags(context.Background(), &ec2.CreateTagsInput{
+			Resources: []string{change.imageID},
+			Tags:      convertTagMapToSlice(change.set),
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Debugf("Changed tags of AMI %s in account %s, region %s", change.imageID, change.account, change.region)
+
+	return nil
+}
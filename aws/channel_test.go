@@ -0,0 +1,161 @@
+package aws
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+func TestTagChangeInverseFor(t *testing.T) {
+	tests := []struct {
+		name      string
+		set       map[string]string
+		unset     []string
+		current   map[string]string
+		wantSet   map[string]string
+		wantUnset []string
+	}{
+		{
+			name:    "no changes",
+			current: map[string]string{"Name": "web"},
+			wantSet: map[string]string{},
+		},
+		{
+			name:      "set a new tag",
+			set:       map[string]string{"channel": "production"},
+			current:   map[string]string{"Name": "web"},
+			wantSet:   map[string]string{},
+			wantUnset: []string{"channel"},
+		},
+		{
+			name:    "overwrite an existing tag",
+			set:     map[string]string{"channel": "production"},
+			current: map[string]string{"channel": "staging"},
+			wantSet: map[string]string{"channel": "staging"},
+		},
+		{
+			name:    "unset an existing tag",
+			unset:   []string{"channel"},
+			current: map[string]string{"channel": "production", "Name": "web"},
+			wantSet: map[string]string{"channel": "production"},
+		},
+		{
+			name:    "unset a missing tag",
+			unset:   []string{"channel"},
+			current: map[string]string{"Name": "web"},
+			wantSet: map[string]string{},
+		},
+		{
+			name:  "set and unset",
+			set:   map[string]string{"ami-manager:production:successor": "ami-2", "ami-manager:production:previous": "ami-0"},
+			unset: []string{"channel"},
+			current: map[string]string{
+				"channel":                         "production",
+				"ami-manager:production:previous": "ami-1",
+			},
+			wantSet: map[string]string{
+				"channel":                         "production",
+				"ami-manager:production:previous": "ami-1",
+			},
+			wantUnset: []string{"ami-manager:production:successor"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			change := tagChange{
+				account: "123456789012",
+				region:  "eu-west-1",
+				imageID: "ami-0123456789abcdef0",
+				set:     tt.set,
+				unset:   tt.unset,
+			}
+
+			inverse := change.inverseFor(tt.current)
+
+			if inverse.account != change.account || inverse.region != change.region || inverse.imageID != change.imageID {
+				t.Errorf("inverseFor() changes image %s in account %s, region %s", inverse.imageID, inverse.account, inverse.region)
+			}
+
+			if !reflect.DeepEqual(inverse.set, tt.wantSet) {
+				t.Errorf("inverseFor() set = %v, want %v", inverse.set, tt.wantSet)
+			}
+
+			sort.Strings(inverse.unset)
+			if !reflect.DeepEqual(inverse.unset, tt.wantUnset) {
+				t.Errorf("inverseFor() unset = %v, want %v", inverse.unset, tt.wantUnset)
+			}
+		})
+	}
+}
+
+func TestChannelSortHolders(t *testing.T) {
+	channel := NewChannel("production", DefaultChannelTag)
+
+	holder := func(id string, creationDate string, promotedAt string) ec2Types.Image {
+		image := ec2Types.Image{
+			ImageId:      aws.String(id),
+			CreationDate: aws.String(creationDate),
+		}
+
+		if promotedAt != "" {
+			image.Tags = []ec2Types.Tag{{Key: aws.String(channel.promotedAtTag()), Value: aws.String(promotedAt)}}
+		}
+
+		return image
+	}
+
+	tests := []struct {
+		name    string
+		holders []ec2Types.Image
+		want    []string
+	}{
+		{
+			name: "newest promoted first",
+			holders: []ec2Types.Image{
+				holder("ami-old", "2019-01-01T00:00:00.000Z", "2019-01-02T00:00:00Z"),
+				holder("ami-new", "2019-02-01T00:00:00.000Z", "2019-02-02T00:00:00Z"),
+			},
+			want: []string{"ami-new", "ami-old"},
+		},
+		{
+			name: "older AMI promoted again",
+			holders: []ec2Types.Image{
+				holder("ami-new", "2019-02-01T00:00:00.000Z", "2019-02-02T00:00:00Z"),
+				holder("ami-old", "2019-01-01T00:00:00.000Z", "2019-03-01T00:00:00Z"),
+			},
+			want: []string{"ami-old", "ami-new"},
+		},
+		{
+			name: "tagged by hand last, newest created first",
+			holders: []ec2Types.Image{
+				holder("ami-hand-old", "2019-01-01T00:00:00.000Z", ""),
+				holder("ami-promoted", "2019-01-15T00:00:00.000Z", "2019-01-16T00:00:00Z"),
+				holder("ami-hand-new", "2019-02-01T00:00:00.000Z", ""),
+			},
+			want: []string{"ami-promoted", "ami-hand-new", "ami-hand-old"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := channel.sortHolders(tt.holders)
+
+			if err != nil {
+				t.Fatalf("sortHolders() error = %v", err)
+			}
+
+			got := make([]string, len(tt.holders))
+			for i, holder := range tt.holders {
+				got[i] = aws.ToString(holder.ImageId)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sortHolders() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,8 +2,13 @@ package aws
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
 	"os"
+	"slices"
+	"strings"
+	"sync"
 
 	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -19,7 +24,7 @@ func SetLogLevel(level string) {
 	logLevel, err := log.ParseLevel(level)
 
 	if err != nil {
-		log.Fatal("Invalid loglevel: %s", level)
+		log.Fatalf("Invalid loglevel: %s", level)
 	}
 
 	log.SetLevel(logLevel)
@@ -30,61 +35,269 @@ type ConfigurationManager struct {
 	defaultRegion    string
 	defaultProfile   string
 	defaultAccountID *string
+	partition        string
 
 	regions  []string
 	accounts []string
 
+	// mu guards the configurations, which are created when an account is first used
+	mu                sync.Mutex
 	configsPerAccount map[string]awsv2.Config
-
-	role string
+	// configsPerChain are the configurations of the roles that are assumed before the role in an account, by the
+	// ARN's of the roles in the chain, so their credentials are shared by every account
+	configsPerChain map[string]awsv2.Config
+	// rolesPerAccount are the roles of accounts that don't use the default role, e.g. the source account
+	rolesPerAccount map[string]string
+	// resolvedAccounts are the accounts whose credentials have been retrieved, and failedAccounts the accounts in which
+	// the role could not be assumed, so the role is assumed only once per account
+	resolvedAccounts map[string]bool
+	failedAccounts   map[string]error
+	// skipFailedAccounts continues without the accounts in which the role can't be assumed, instead of failing
+	skipFailedAccounts bool
+
+	role           string
+	accountsConfig *AccountsConfig
+	endpoints      *EndpointOverrides
 }
 
-func NewConfigurationManager() *ConfigurationManager {
-	return NewConfigurationManagerForRegionsAndAccounts(make([]string, 0), make([]string, 0), "")
+func NewConfigurationManager() (*ConfigurationManager, error) {
+	return NewConfigurationManagerForRegionsAndAccounts(make([]string, 0), make([]string, 0), "", nil, nil, nil)
 }
 
-func NewConfigurationManagerForRegionsAndAccounts(regions []string, accounts []string, role string) *ConfigurationManager {
+// NewConfigurationManagerForRegionsAndAccounts loads the default configuration. The role in an account is assumed when
+// the account is first used. The accounts configuration overrides how the role is assumed per account, and can be nil.
+// The credentials replace the default credentials, e.g. with static STS credentials, and the endpoints override the
+// endpoints of EC2 and STS. Both can be nil as well.
+func NewConfigurationManagerForRegionsAndAccounts(regions []string, accounts []string, role string, accountsConfig *AccountsConfig, credentials *CredentialsProvider, endpoints *EndpointOverrides) (*ConfigurationManager, error) {
 	cm := &ConfigurationManager{
-		regions:  regions,
-		accounts: accounts,
-		role:     role,
+		regions:           regions,
+		accounts:          accounts,
+		role:              role,
+		accountsConfig:    accountsConfig,
+		endpoints:         endpoints,
+		configsPerAccount: make(map[string]awsv2.Config),
+		configsPerChain:   make(map[string]awsv2.Config),
+		rolesPerAccount:   make(map[string]string),
+		resolvedAccounts:  make(map[string]bool),
+		failedAccounts:    make(map[string]error),
 	}
 
 	log.Debug("Setting defaults")
-	conf, err := config.LoadDefaultConfig(context.TODO())
+	var options []func(*config.LoadOptions) error
+	if credentials != nil {
+		options = append(options, config.WithCredentialsProvider(awsv2.NewCredentialsCache(credentials)))
+	}
+
+	conf, err := config.LoadDefaultConfig(context.TODO(), options...)
 
 	if err != nil {
-		panic("unable to load SDK config, " + err.Error())
+		return nil, fmt.Errorf("unable to load SDK config: %w", err)
 	}
 
 	cm.defaultConfig = conf
 	cm.defaultProfile = os.Getenv(ProfileString)
 	cm.defaultRegion = conf.Region
 
-	stsService := sts.NewFromConfig(conf)
+	endpoints.logOverrides()
+
+	stsService := cm.newSTSClient(conf)
 
 	defaultAccountID, err := stsService.GetCallerIdentity(context.TODO(), &sts.GetCallerIdentityInput{})
 	if err != nil {
-		panic("unable to load defaultAccountId, " + err.Error())
+		return nil, fmt.Errorf("unable to load defaultAccountId: %w", err)
 	}
 
 	cm.defaultAccountID = defaultAccountID.Account
+	cm.partition = detectPartition(defaultAccountID.Arn, cm.defaultRegion)
 
-	cm.configsPerAccount = make(map[string]awsv2.Config)
-	for _, account := range cm.accounts {
-		// you shouldn't assume role in your own account. We expect this user to have sufficient permissions
-		if account == *cm.defaultAccountID {
+	log.Debugf("Using partition %s", cm.partition)
+
+	err = cm.ValidateRegions(endpoints.regions())
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint: %w", err)
+	}
+
+	return cm, nil
+}
+
+// AddAccounts adds accounts that are not configured yet, e.g. the accounts found in the organization
+func (cm *ConfigurationManager) AddAccounts(accounts []string) {
+	for _, account := range accounts {
+		if account == *cm.defaultAccountID || slices.Contains(cm.accounts, account) {
 			continue
 		}
 
-		confCopy := conf.Copy()
+		cm.accounts = append(cm.accounts, account)
+	}
+}
+
+// AddSourceAccount configures the role to assume in the account that owns the source AMI, unless it is the default
+// account or one of the accounts, which use the default role
+func (cm *ConfigurationManager) AddSourceAccount(account string, role string) {
+	if account == *cm.defaultAccountID || slices.Contains(cm.accounts, account) {
+		log.Debugf("Source account %s is already configured", account)
+		return
+	}
+
+	cm.rolesPerAccount[account] = role
+}
+
+// SetSkipFailedAccounts makes the operations that need the credentials of the accounts continue without the accounts in
+// which the role can't be assumed, instead of failing
+func (cm *ConfigurationManager) SetSkipFailedAccounts(skip bool) {
+	cm.skipFailedAccounts = skip
+}
+
+// withCredentials returns the accounts whose credentials can be retrieved, for the operations that act in the accounts
+// themselves, e.g. tagging. The role is assumed when an account is first used here, concurrently, and the result is
+// kept. Sharing with an account needs no credentials in it, so the accounts in which the role can't be assumed are
+// only left out here. They are reported as failed, and an error is returned unless skipFailedAccounts is set.
+func (cm *ConfigurationManager) withCredentials(accounts []string) ([]string, error) {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs = make(map[string]error)
+	)
+
+	for _, account := range accounts {
+		wg.Add(1)
+		go func(account string) {
+			defer wg.Done()
+
+			err := cm.retrieveCredentials(account)
+
+			if err != nil {
+				mu.Lock()
+				errs[account] = err
+				mu.Unlock()
+			}
+		}(account)
+	}
+
+	wg.Wait()
+
+	var (
+		usable []string
+		joined []error
+	)
+
+	for _, account := range accounts {
+		if err, failed := errs[account]; failed {
+			joined = append(joined, fmt.Errorf("unable to assume the role in account %s: %w", account, err))
+		} else {
+			usable = append(usable, account)
+		}
+	}
+
+	if len(joined) > 0 && !cm.skipFailedAccounts {
+		return nil, fmt.Errorf("%w, use --skip-failed-accounts to continue with the other accounts", errors.Join(joined...))
+	}
+
+	return usable, nil
+}
+
+// retrieveCredentials assumes the role in an account the first time it is used, and returns the error of that
+// attempt afterwards
+func (cm *ConfigurationManager) retrieveCredentials(account string) error {
+	if account == *cm.defaultAccountID {
+		return nil
+	}
+
+	cm.mu.Lock()
+	if err, failed := cm.failedAccounts[account]; failed {
+		cm.mu.Unlock()
+		return err
+	}
+	resolved := cm.resolvedAccounts[account]
+	cm.mu.Unlock()
+
+	if resolved {
+		return nil
+	}
+
+	_, err := cm.getConfigurationForAccount(account).Credentials.Retrieve(context.Background())
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	if err != nil {
+		if _, failed := cm.failedAccounts[account]; !failed && cm.skipFailedAccounts {
+			log.Warnf("Continuing without account %s, the role could not be assumed: %s", account, err)
+		}
 
-		confCopy.Credentials = stscreds.NewAssumeRoleProvider(stsService, "arn:aws:iam::"+account+":role/"+cm.role)
+		cm.failedAccounts[account] = err
 
-		cm.configsPerAccount[account] = confCopy
+		return err
 	}
 
-	return cm
+	cm.resolvedAccounts[account] = true
+
+	return nil
+}
+
+// FailedAccounts returns the accounts in which the role could not be assumed, sorted
+func (cm *ConfigurationManager) FailedAccounts() []string {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	return sortedKeys(cm.failedAccounts)
+}
+
+func (cm *ConfigurationManager) assumeRoleConfiguration(account string, role string) awsv2.Config {
+	accountConfig := cm.accountsConfig.forAccount(account, role, cm.partition)
+	roleArn := accountConfig.roleArnFor(account)
+
+	if accountConfig.Partition != cm.partition {
+		log.Warnf("The role in account %s is in partition %s, but the credentials are for partition %s", account, accountConfig.Partition, cm.partition)
+	}
+
+	if accountConfig.credentials != nil {
+		log.Debugf("Using the STS credentials from %s in account %s", accountConfig.CredentialsFile, account)
+
+		confCopy := cm.defaultConfig.Copy()
+		confCopy.Credentials = awsv2.NewCredentialsCache(accountConfig.credentials)
+
+		return confCopy
+	}
+
+	log.Debugf("Assuming role %s in account %s with session name %s", roleArn, account, accountConfig.SessionName)
+
+	base := cm.chainConfiguration(accountConfig.Via, accountConfig.SessionName)
+	confCopy := base.Copy()
+
+	confCopy.Credentials = awsv2.NewCredentialsCache(stscreds.NewAssumeRoleProvider(cm.newSTSClient(base), roleArn, accountConfig.assumeRoleOptions))
+
+	return confCopy
+}
+
+// chainConfiguration returns the configuration with the credentials of the last role in a chain of roles, that are
+// assumed in order starting from the default credentials. The configurations are cached per chain and session name, so
+// every session in the chain has the session name of the account. It must be called with the lock held.
+func (cm *ConfigurationManager) chainConfiguration(chain []string, sessionName string) awsv2.Config {
+	if len(chain) == 0 {
+		return cm.defaultConfig
+	}
+
+	key := sessionName + ":" + strings.Join(chain, ",")
+
+	if conf, ok := cm.configsPerChain[key]; ok {
+		return conf
+	}
+
+	base := cm.chainConfiguration(chain[:len(chain)-1], sessionName)
+	roleArn := chain[len(chain)-1]
+
+	log.Debugf("Assuming role %s in the role chain", roleArn)
+
+	conf := base.Copy()
+	conf.Credentials = awsv2.NewCredentialsCache(stscreds.NewAssumeRoleProvider(cm.newSTSClient(base), roleArn, func(options *stscreds.AssumeRoleOptions) {
+		options.RoleSessionName = sessionName
+	}))
+
+	cm.configsPerChain[key] = conf
+
+	return conf
 }
 
 func (cm *ConfigurationManager) GetDefaultRegion() string {
@@ -95,11 +308,6 @@ func (cm *ConfigurationManager) GetDefaultAccountID() *string {
 	return cm.defaultAccountID
 }
 
-func (cm *ConfigurationManager) loadConfiguration() {
-	log.Debug("Load configuration")
-
-}
-
 func (cm *ConfigurationManager) GetConfigurationForDefaultAccount() awsv2.Config {
 	log.Debug("GetConfigurationForDefaultAccount")
 	return cm.getConfigurationForAccount(*cm.defaultAccountID)
@@ -110,6 +318,21 @@ func (cm *ConfigurationManager) getConfigurationForAccount(account string) awsv2
 	if account == *cm.defaultAccountID {
 		return cm.defaultConfig
 	}
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	if conf, ok := cm.configsPerAccount[account]; ok {
+		return conf
+	}
+
+	role := cm.role
+	if accountRole, ok := cm.rolesPerAccount[account]; ok {
+		role = accountRole
+	}
+
+	cm.configsPerAccount[account] = cm.assumeRoleConfiguration(account, role)
+
 	return cm.configsPerAccount[account]
 }
 
@@ -132,3 +355,31 @@ func (cm *ConfigurationManager) getConfigurationForAccountAndRegion(account stri
 func (cm *ConfigurationManager) getAccounts() []string {
 	return cm.accounts
 }
+
+// isConfiguredAccount returns whether credentials are configured for an account, i.e. the default account, one of the
+// accounts or the source account
+func (cm *ConfigurationManager) isConfiguredAccount(account string) bool {
+	if account == *cm.defaultAccountID || slices.Contains(cm.accounts, account) {
+		return true
+	}
+
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	_, ok := cm.rolesPerAccount[account]
+
+	return ok
+}
+
+// getAllAccounts returns the default account, followed by the other accounts
+func (cm *ConfigurationManager) getAllAccounts() []string {
+	all := []string{*cm.defaultAccountID}
+
+	for _, account := range cm.accounts {
+		if account != *cm.defaultAccountID {
+			all = append(all, account)
+		}
+	}
+
+	return all
+}
@@ -0,0 +1,59 @@
+package aws
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestWithCredentials(t *testing.T) {
+	tests := []struct {
+		name               string
+		skipFailedAccounts bool
+		want               []string
+		wantErr            bool
+	}{
+		{
+			name:    "fails on an account without credentials",
+			wantErr: true,
+		},
+		{
+			name:               "leaves out the accounts without credentials",
+			skipFailedAccounts: true,
+			want:               []string{"111111111111", "222222222222"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cm := &ConfigurationManager{
+				defaultAccountID:   awsv2.String("111111111111"),
+				accounts:           []string{"222222222222", "333333333333"},
+				resolvedAccounts:   map[string]bool{"222222222222": true},
+				failedAccounts:     map[string]error{"333333333333": errors.New("access denied")},
+				skipFailedAccounts: tt.skipFailedAccounts,
+			}
+
+			got, err := cm.withCredentials(cm.getAllAccounts())
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("withCredentials() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("withCredentials() = %v, want %v", got, tt.want)
+			}
+
+			// the accounts without credentials are still shared with
+			if got, want := cm.getAccounts(), []string{"222222222222", "333333333333"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("getAccounts() = %v, want %v", got, want)
+			}
+
+			if got, want := cm.FailedAccounts(), []string{"333333333333"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("FailedAccounts() = %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -1,25 +1,89 @@
 package aws
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"os"
+	"time"
 
 	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
 	stsTypes "github.com/aws/aws-sdk-go-v2/service/sts/types"
 )
 
+const (
+	// StsCredentialsEnvVar is the environment variable with the path of an STS credentials file
+	StsCredentialsEnvVar string = "AMI_MANAGER_STS_CREDENTIALS"
+)
+
+// CredentialsProvider provides static STS credentials that were obtained outside this tool, e.g. by an
+// AssumeRoleWithWebIdentity exchange in CI
 type CredentialsProvider struct {
 	*stsTypes.Credentials
 }
 
-func (s CredentialsProvider) Retrieve() (awsv2.Credentials, error) {
+var _ awsv2.CredentialsProvider = CredentialsProvider{}
+
+func (s CredentialsProvider) Retrieve(ctx context.Context) (awsv2.Credentials, error) {
 	if s.Credentials == nil {
 		return awsv2.Credentials{}, errors.New("sts credentials are nil")
 	}
 
+	if s.AccessKeyId == nil || s.SecretAccessKey == nil || s.SessionToken == nil || s.Expiration == nil {
+		return awsv2.Credentials{}, errors.New("sts credentials are incomplete")
+	}
+
+	if time.Now().After(*s.Expiration) {
+		return awsv2.Credentials{}, fmt.Errorf("sts credentials expired at %s", s.Expiration.Format(time.RFC3339))
+	}
+
 	return awsv2.Credentials{
 		AccessKeyID:     *s.AccessKeyId,
 		SecretAccessKey: *s.SecretAccessKey,
 		SessionToken:    *s.SessionToken,
+		Source:          "CredentialsProvider",
+		CanExpire:       true,
 		Expires:         *s.Expiration,
 	}, nil
 }
+
+// LoadCredentialsProvider reads STS credentials from a JSON file, formatted like the output of aws sts assume-role or
+// aws sts assume-role-with-web-identity, or like the Credentials in that output
+func LoadCredentialsProvider(path string) (*CredentialsProvider, error) {
+	content, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var output struct {
+		Credentials *stsTypes.Credentials
+	}
+
+	err = json.Unmarshal(content, &output)
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse STS credentials %s: %w", path, err)
+	}
+
+	if output.Credentials == nil {
+		output.Credentials = &stsTypes.Credentials{}
+
+		err = json.Unmarshal(content, output.Credentials)
+
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse STS credentials %s: %w", path, err)
+		}
+	}
+
+	provider := &CredentialsProvider{Credentials: output.Credentials}
+
+	_, err = provider.Retrieve(context.Background())
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid STS credentials in %s: %w", path, err)
+	}
+
+	return provider, nil
+}
@@ -0,0 +1,86 @@
+package aws
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadCredentialsProvider(t *testing.T) {
+	expiration := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	expired := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{
+			name:    "output of assume-role",
+			content: `{"Credentials": {"AccessKeyId": "ASIAEXAMPLE", "SecretAccessKey": "secret", "SessionToken": "token", "Expiration": "` + expiration + `"}, "AssumedRoleUser": {"Arn": "arn:aws:sts::123456789012:assumed-role/ci/session"}}`,
+		},
+		{
+			name:    "credentials only",
+			content: `{"AccessKeyId": "ASIAEXAMPLE", "SecretAccessKey": "secret", "SessionToken": "token", "Expiration": "` + expiration + `"}`,
+		},
+		{
+			name:    "expired",
+			content: `{"Credentials": {"AccessKeyId": "ASIAEXAMPLE", "SecretAccessKey": "secret", "SessionToken": "token", "Expiration": "` + expired + `"}}`,
+			wantErr: true,
+		},
+		{
+			name:    "without session token",
+			content: `{"AccessKeyId": "ASIAEXAMPLE", "SecretAccessKey": "secret", "Expiration": "` + expiration + `"}`,
+			wantErr: true,
+		},
+		{
+			name:    "without expiration",
+			content: `{"AccessKeyId": "ASIAEXAMPLE", "SecretAccessKey": "secret", "SessionToken": "token"}`,
+			wantErr: true,
+		},
+		{
+			name:    "empty",
+			content: `{}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid JSON",
+			content: `{"Credentials": `,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "credentials.json")
+
+			err := os.WriteFile(path, []byte(tt.content), 0o600)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			provider, err := LoadCredentialsProvider(path)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadCredentialsProvider() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			credentials, err := provider.Retrieve(context.Background())
+
+			if err != nil {
+				t.Fatalf("Retrieve() error = %v", err)
+			}
+
+			if credentials.AccessKeyID != "ASIAEXAMPLE" || credentials.SecretAccessKey != "secret" || credentials.SessionToken != "token" || !credentials.CanExpire {
+				t.Errorf("Retrieve() = %+v, want the credentials from the file", credentials)
+			}
+		})
+	}
+}
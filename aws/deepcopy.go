@@ -0,0 +1,322 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	kmsTypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// DeepCopy copies the AMI into every target account, after it has been shared with them. Every account then owns an
+// independent copy, that keeps working when the AMI in this account is removed. The copies are encrypted with a KMS
+// key of the target account. The target accounts are granted the use of the key of an encrypted AMI while copying.
+type DeepCopy struct {
+	// KmsKeyID is the key in the target accounts, e.g. alias/ami. When empty, the default EBS key of the account is used.
+	KmsKeyID string
+}
+
+func NewDeepCopy(kmsKeyID string) *DeepCopy {
+	return &DeepCopy{
+		KmsKeyID: kmsKeyID,
+	}
+}
+
+// copyToAccounts copies the AMI in a region into every target account, and returns the ID's of the copies per account
+func (deepCopy *DeepCopy) copyToAccounts(ami *Ami, region string, relatedAmi *Ami) (map[string]string, error) {
+	accounts, err := ConfigManager.withCredentials(ConfigManager.getAccounts())
+
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []string
+	for _, account := range accounts {
+		if account != *ConfigManager.defaultAccountID {
+			targets = append(targets, account)
+		}
+	}
+
+	if len(targets) == 0 {
+		return nil, nil
+	}
+
+	// the target accounts need permission to launch the AMI and to read its snapshots to copy it
+	err = relatedAmi.setOwners(targets)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ec2Service := getEC2ServiceForAccountAndRegion(*ConfigManager.defaultAccountID, region)
+
+	err = addCreateVolumePermissions(relatedAmi.AWSImage, targets, ec2Service)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// the snapshots of an encrypted AMI can only be copied with the use of their key, the copies are re-encrypted with
+	// the key of the target account
+	grants, err := grantKeyUsage(relatedAmi.AWSImage, targets, region)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		copies = make(map[string]string)
+		errs   []error
+	)
+
+	for _, account := range targets {
+		wg.Add(1)
+		go func(account string) {
+			defer wg.Done()
+
+			imageID, err := deepCopy.copyToAccount(ami, account, region, relatedAmi)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				errs = append(errs, fmt.Errorf("unable to copy AMI %s to account %s in region %s: %w", relatedAmi.SourceAmiID, account, region, err))
+				return
+			}
+
+			copies[account] = imageID
+		}(account)
+	}
+
+	wg.Wait()
+
+	// the grants are only needed while copying
+	err = grants.revoke()
+
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	return copies, errors.Join(errs...)
+}
+
+func (deepCopy *DeepCopy) copyToAccount(ami *Ami, account string, region string, relatedAmi *Ami) (string, error) {
+	existing, err := ami.findCopiesForAccount(account, region)
+
+	if err != nil {
+		return "", err
+	}
+
+	if len(existing) > 0 {
+		log.Infof("Account %s already owns copy %s of AMI %s in region %s", account, *existing[0].ImageId, ami.SourceAmiID, region)
+		return *existing[0].ImageId, nil
+	}
+
+	tags, err := ami.tagsFor(region, account)
+
+	if err != nil {
+		return "", err
+	}
+
+	tags = append(tags, ami.lineageTags()...)
+	tags = append(tags, ec2Types.Tag{
+		Key:   aws.String(SourceAccountTag),
+		Value: aws.String(*ConfigManager.defaultAccountID),
+	})
+
+	log.Infof("Copying AMI %s to account %s in region %s", relatedAmi.SourceAmiID, account, region)
+	copyImageInput := &ec2.CopyImageInput{
+		Name:          aws.String(relatedAmi.SourceAmiName),
+		Description:   optionalString(relatedAmi.Description),
+		SourceRegion:  aws.String(region),
+		SourceImageId: aws.String(relatedAmi.SourceAmiID),
+		Encrypted:     aws.Bool(true),
+		KmsKeyId:      optionalString(deepCopy.KmsKeyID),
+		TagSpecifications: []ec2Types.TagSpecification{
+			{
+				ResourceType: ec2Types.ResourceTypeImage,
+				Tags:         tags,
+			},
+			{
+				ResourceType: ec2Types.ResourceTypeSnapshot,
+				Tags:         tags,
+			},
+		},
+	}
+	ec2Service := getEC2ServiceForAccountAndRegion(account, region)
+
+	output, err := ec2Service.CopyImage(context.Background(), copyImageInput)
+
+	if err != nil {
+		return "", err
+	}
+
+	log.Infof("New AMI ID in account %s: %s", account, *output.ImageId)
+
+	err = waitUntilAvailable(ec2Service, *output.ImageId)
+
+	if err != nil {
+		return "", err
+	}
+
+	return *output.ImageId, nil
+}
+
+// keyGrants are the grants of KMS keys to the target accounts, by key ARN
+type keyGrants struct {
+	kmsService *kms.Client
+	grantIDs   map[string][]string
+}
+
+// grantKeyUsage grants the target accounts the use of the KMS keys that the snapshots of an encrypted image are
+// encrypted with, so they can copy the snapshots. The keys must be customer managed keys, as the AWS managed key of EBS
+// can't be used by other accounts.
+func grantKeyUsage(image *ec2Types.Image, accounts []string, region string) (*keyGrants, error) {
+	grants := &keyGrants{grantIDs: make(map[string][]string)}
+
+	if !isEncrypted(image) {
+		return grants, nil
+	}
+
+	keyIDs, err := snapshotKmsKeyIDs(image, region)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ConfigManager.endpoints.warnDefaultEndpoint(serviceKMS)
+	grants.kmsService = kms.NewFromConfig(ConfigManager.getConfigurationForDefaultAccountAndRegion(region))
+
+	for _, keyID := range keyIDs {
+		output, err := grants.kmsService.DescribeKey(context.Background(), &kms.DescribeKeyInput{
+			KeyId: aws.String(keyID),
+		})
+
+		if err != nil {
+			return nil, fmt.Errorf("unable to describe key %s of AMI %s: %w", keyID, *image.ImageId, err)
+		}
+
+		if output.KeyMetadata.KeyManager == kmsTypes.KeyManagerTypeAws {
+			return nil, fmt.Errorf("AMI %s in region %s is encrypted with the AWS managed key %s, which can't be used by other accounts. Copy it with a customer managed key first", *image.ImageId, region, keyID)
+		}
+
+		for _, account := range accounts {
+			log.Debugf("Granting account %s the use of key %s", account, keyID)
+
+			grant, err := grants.kmsService.CreateGrant(context.Background(), &kms.CreateGrantInput{
+				KeyId:            aws.String(keyID),
+				GranteePrincipal: aws.String("arn:" + ConfigManager.partition + ":iam::" + account + ":root"),
+				Name:             aws.String("ami-manager-deep-copy"),
+				Operations: []kmsTypes.GrantOperation{
+					kmsTypes.GrantOperationDecrypt,
+					kmsTypes.GrantOperationDescribeKey,
+					kmsTypes.GrantOperationCreateGrant,
+					kmsTypes.GrantOperationGenerateDataKeyWithoutPlaintext,
+					kmsTypes.GrantOperationReEncryptFrom,
+				},
+			})
+
+			if err != nil {
+				return nil, errors.Join(fmt.Errorf("unable to grant account %s the use of key %s: %w", account, keyID, err), grants.revoke())
+			}
+
+			grants.grantIDs[keyID] = append(grants.grantIDs[keyID], *grant.GrantId)
+		}
+	}
+
+	return grants, nil
+}
+
+// revoke revokes the grants
+func (grants *keyGrants) revoke() error {
+	var errs []error
+
+	for keyID, grantIDs := range grants.grantIDs {
+		for _, grantID := range grantIDs {
+			_, err := grants.kmsService.RevokeGrant(context.Background(), &kms.RevokeGrantInput{
+				KeyId:   aws.String(keyID),
+				GrantId: aws.String(grantID),
+			})
+
+			if err != nil {
+				errs = append(errs, fmt.Errorf("unable to revoke grant %s of key %s: %w", grantID, keyID, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// snapshotKmsKeyIDs returns the ARN's of the KMS keys the snapshots of an image are encrypted with. DescribeImages
+// doesn't return the keys, so the snapshots are described.
+func snapshotKmsKeyIDs(image *ec2Types.Image, region string) ([]string, error) {
+	var snapshotIDs []string
+	for _, mapping := range image.BlockDeviceMappings {
+		if mapping.Ebs != nil && mapping.Ebs.SnapshotId != nil {
+			snapshotIDs = append(snapshotIDs, *mapping.Ebs.SnapshotId)
+		}
+	}
+
+	if len(snapshotIDs) == 0 {
+		return nil, nil
+	}
+
+	ec2Service := getEC2ServiceForAccountAndRegion(*ConfigManager.defaultAccountID, region)
+
+	output, err := ec2Service.DescribeSnapshots(context.Background(), &ec2.DescribeSnapshotsInput{
+		SnapshotIds: snapshotIDs,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	keyIDs := make(map[string]bool)
+	for _, snapshot := range output.Snapshots {
+		if aws.ToBool(snapshot.Encrypted) && snapshot.KmsKeyId != nil {
+			keyIDs[*snapshot.KmsKeyId] = true
+		}
+	}
+
+	return sortedKeys(keyIDs), nil
+}
+
+// addCreateVolumePermissions shares the snapshots of an image with accounts, so they can copy the image
+func addCreateVolumePermissions(image *ec2Types.Image, accounts []string, ec2Service *ec2.Client) error {
+	permissions := make([]ec2Types.CreateVolumePermission, 0, len(accounts))
+	for _, account := range accounts {
+		permissions = append(permissions, ec2Types.CreateVolumePermission{UserId: aws.String(account)})
+	}
+
+	for _, mapping := range image.BlockDeviceMappings {
+		if mapping.Ebs == nil || mapping.Ebs.SnapshotId == nil {
+			continue
+		}
+
+		log.Debugf("Adding create volume permissions to snapshot %s", *mapping.Ebs.SnapshotId)
+
+		modifySnapshotAttributeInput := &ec2.ModifySnapshotAttributeInput{
+			SnapshotId: mapping.Ebs.SnapshotId,
+			Attribute:  ec2Types.SnapshotAttributeNameCreateVolumePermission,
+			CreateVolumePermission: &ec2Types.CreateVolumePermissionModifications{
+				Add: permissions,
+			},
+		}
+
+		_, err := ec2Service.ModifySnapshotAttribute(context.Background(), modifySnapshotAttributeInput)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
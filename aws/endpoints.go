@@ -0,0 +1,203 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	awsv2 "github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	smithyendpoints "github.com/aws/smithy-go/endpoints"
+	log "github.com/sirupsen/logrus"
+)
+
+// The services of which the endpoints can be overridden
+const (
+	ServiceEC2 string = "ec2"
+	ServiceSTS string = "sts"
+)
+
+// The services that always use their default endpoints
+const (
+	serviceSSM           string = "ssm"
+	serviceKMS           string = "kms"
+	serviceOrganizations string = "organizations"
+	serviceImageBuilder  string = "imagebuilder"
+)
+
+// EndpointOverrides are the endpoints of services, e.g. LocalStack or VPC interface endpoints, for every region or per
+// region. The endpoint of a region takes precedence over the endpoint for every region.
+type EndpointOverrides struct {
+	// endpoints by service and region, the empty region is every region
+	endpoints map[string]map[string]string
+
+	mu sync.Mutex
+	// warned are the services that were reported to use their default endpoints
+	warned map[string]bool
+}
+
+// ParseEndpointOverrides parses endpoints formatted as service=url for every region, or as service:region=url, e.g.
+// ec2=http://localhost:4566 or sts:eu-west-1=https://vpce-0123-abcd.sts.eu-west-1.vpce.amazonaws.com
+func ParseEndpointOverrides(values []string) (*EndpointOverrides, error) {
+	overrides := &EndpointOverrides{
+		endpoints: make(map[string]map[string]string),
+		warned:    make(map[string]bool),
+	}
+
+	for _, value := range values {
+		key, endpoint, found := strings.Cut(value, "=")
+
+		if !found {
+			return nil, fmt.Errorf("invalid endpoint %q, expected service=url or service:region=url", value)
+		}
+
+		service, region, hasRegion := strings.Cut(key, ":")
+
+		if service != ServiceEC2 && service != ServiceSTS {
+			return nil, fmt.Errorf("invalid endpoint %q, the service must be %s or %s", value, ServiceEC2, ServiceSTS)
+		}
+
+		if hasRegion && !regionPattern.MatchString(region) {
+			return nil, fmt.Errorf("invalid endpoint %q, %q is not a region", value, region)
+		}
+
+		parsed, err := url.Parse(endpoint)
+
+		if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			return nil, fmt.Errorf("invalid endpoint %q, expected a URL like https://host:port", value)
+		}
+
+		if overrides.endpoints[service] == nil {
+			overrides.endpoints[service] = make(map[string]string)
+		}
+
+		overrides.endpoints[service][region] = endpoint
+	}
+
+	return overrides, nil
+}
+
+// regions returns the regions that have their own endpoints, sorted by name
+func (overrides *EndpointOverrides) regions() []string {
+	if overrides == nil {
+		return nil
+	}
+
+	unique := make(map[string]bool)
+
+	for _, endpoints := range overrides.endpoints {
+		for region := range endpoints {
+			if region != "" {
+				unique[region] = true
+			}
+		}
+	}
+
+	return sortedKeys(unique)
+}
+
+// warnDefaultEndpoint warns once per service that a service doesn't use the overridden endpoints
+func (overrides *EndpointOverrides) warnDefaultEndpoint(service string) {
+	if overrides == nil || len(overrides.endpoints) == 0 {
+		return
+	}
+
+	overrides.mu.Lock()
+	defer overrides.mu.Unlock()
+
+	if overrides.warned[service] {
+		return
+	}
+
+	overrides.warned[service] = true
+
+	log.Warnf("--endpoint only applies to %s and %s, %s uses its default endpoint", ServiceEC2, ServiceSTS, service)
+}
+
+// forRegion returns the endpoint of a service in a region, or an empty string when it is not overridden
+func (overrides *EndpointOverrides) forRegion(service string, region string) string {
+	if overrides == nil {
+		return ""
+	}
+
+	if endpoint, ok := overrides.endpoints[service][region]; ok {
+		return endpoint
+	}
+
+	return overrides.endpoints[service][""]
+}
+
+// ec2EndpointResolver resolves the overridden endpoints, and falls back to the default resolver of the SDK
+type ec2EndpointResolver struct {
+	overrides *EndpointOverrides
+	fallback  ec2.EndpointResolverV2
+}
+
+func (resolver *ec2EndpointResolver) ResolveEndpoint(ctx context.Context, params ec2.EndpointParameters) (smithyendpoints.Endpoint, error) {
+	if endpoint := resolver.overrides.forRegion(ServiceEC2, awsv2.ToString(params.Region)); endpoint != "" {
+		params.Endpoint = awsv2.String(endpoint)
+	}
+
+	return resolver.fallback.ResolveEndpoint(ctx, params)
+}
+
+// stsEndpointResolver resolves the overridden endpoints, and falls back to the default resolver of the SDK
+type stsEndpointResolver struct {
+	overrides *EndpointOverrides
+	fallback  sts.EndpointResolverV2
+}
+
+func (resolver *stsEndpointResolver) ResolveEndpoint(ctx context.Context, params sts.EndpointParameters) (smithyendpoints.Endpoint, error) {
+	if endpoint := resolver.overrides.forRegion(ServiceSTS, awsv2.ToString(params.Region)); endpoint != "" {
+		params.Endpoint = awsv2.String(endpoint)
+	}
+
+	return resolver.fallback.ResolveEndpoint(ctx, params)
+}
+
+// newEC2Client creates an EC2 client that uses the overridden endpoints
+func (cm *ConfigurationManager) newEC2Client(conf awsv2.Config) *ec2.Client {
+	return ec2.NewFromConfig(conf, func(options *ec2.Options) {
+		if cm.endpoints == nil {
+			return
+		}
+
+		options.EndpointResolverV2 = &ec2EndpointResolver{
+			overrides: cm.endpoints,
+			fallback:  ec2.NewDefaultEndpointResolverV2(),
+		}
+	})
+}
+
+// newSTSClient creates an STS client that uses the overridden endpoints
+func (cm *ConfigurationManager) newSTSClient(conf awsv2.Config) *sts.Client {
+	return sts.NewFromConfig(conf, func(options *sts.Options) {
+		if cm.endpoints == nil {
+			return
+		}
+
+		options.EndpointResolverV2 = &stsEndpointResolver{
+			overrides: cm.endpoints,
+			fallback:  sts.NewDefaultEndpointResolverV2(),
+		}
+	})
+}
+
+func (overrides *EndpointOverrides) logOverrides() {
+	if overrides == nil {
+		return
+	}
+
+	for service, endpoints := range overrides.endpoints {
+		for region, endpoint := range endpoints {
+			if region == "" {
+				region = "every region"
+			}
+
+			log.Infof("Using endpoint %s for %s in %s", endpoint, service, region)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package aws
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseEndpointOverrides(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  []string
+		want    map[string]map[string]string
+		wantErr bool
+	}{
+		{
+			name:   "none",
+			values: nil,
+			want:   map[string]map[string]string{},
+		},
+		{
+			name:   "every region",
+			values: []string{"ec2=http://localhost:4566", "sts=http://localhost:4566"},
+			want: map[string]map[string]string{
+				ServiceEC2: {"": "http://localhost:4566"},
+				ServiceSTS: {"": "http://localhost:4566"},
+			},
+		},
+		{
+			name:   "per region",
+			values: []string{"ec2=http://localhost:4566", "ec2:eu-west-1=https://vpce.ec2.eu-west-1.vpce.amazonaws.com"},
+			want: map[string]map[string]string{
+				ServiceEC2: {
+					"":          "http://localhost:4566",
+					"eu-west-1": "https://vpce.ec2.eu-west-1.vpce.amazonaws.com",
+				},
+			},
+		},
+		{
+			name:    "unsupported service",
+			values:  []string{"ssm=http://localhost:4566"},
+			wantErr: true,
+		},
+		{
+			name:    "missing url",
+			values:  []string{"ec2"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid url",
+			values:  []string{"ec2=localhost:4566"},
+			wantErr: true,
+		},
+		{
+			name:    "empty region",
+			values:  []string{"ec2:=http://localhost:4566"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid region",
+			values:  []string{"ec2:eu-wset=http://localhost:4566"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseEndpointOverrides(tt.values)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseEndpointOverrides() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if !reflect.DeepEqual(got.endpoints, tt.want) {
+				t.Errorf("ParseEndpointOverrides() = %v, want %v", got.endpoints, tt.want)
+			}
+		})
+	}
+}
+
+func TestEndpointOverridesForRegion(t *testing.T) {
+	overrides, err := ParseEndpointOverrides([]string{
+		"ec2=http://localhost:4566",
+		"ec2:eu-west-1=https://vpce.ec2.eu-west-1.vpce.amazonaws.com",
+		"sts:us-gov-west-1=https://vpce.sts.us-gov-west-1.vpce.amazonaws.com",
+	})
+
+	if err != nil {
+		t.Fatalf("ParseEndpointOverrides() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		service string
+		region  string
+		want    string
+	}{
+		{
+			name:    "region takes precedence",
+			service: ServiceEC2,
+			region:  "eu-west-1",
+			want:    "https://vpce.ec2.eu-west-1.vpce.amazonaws.com",
+		},
+		{
+			name:    "every region",
+			service: ServiceEC2,
+			region:  "eu-central-1",
+			want:    "http://localhost:4566",
+		},
+		{
+			name:    "other region",
+			service: ServiceSTS,
+			region:  "eu-west-1",
+			want:    "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := overrides.forRegion(tt.service, tt.region); got != tt.want {
+				t.Errorf("forRegion() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if got, want := overrides.regions(), []string{"eu-west-1", "us-gov-west-1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("regions() = %v, want %v", got, want)
+	}
+
+	var none *EndpointOverrides
+
+	if got := none.forRegion(ServiceEC2, "eu-west-1"); got != "" {
+		t.Errorf("forRegion() of nil overrides = %q, want empty", got)
+	}
+}
@@ -0,0 +1,220 @@
+package aws
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// The lineage tags are set on every copy, so the copies can be traced back to the AMI they were copied from.
+const (
+	SourceAmiIDTag  string = "ami-manager:source-ami-id"
+	SourceRegionTag string = "ami-manager:source-region"
+	CopiedAtTag     string = "ami-manager:copied-at"
+
+	// SourceAccountTag is set on deep copies, to the account they were copied from
+	SourceAccountTag string = "ami-manager:source-account"
+)
+
+func (ami *Ami) lineageTags() []ec2Types.Tag {
+	tags := []ec2Types.Tag{
+		{
+			Key:   aws.String(SourceAmiIDTag),
+			Value: aws.String(ami.SourceAmiID),
+		},
+		{
+			Key:   aws.String(SourceRegionTag),
+			Value: aws.String(ami.SourceRegion),
+		},
+	}
+
+	if !ami.CopiedAt.IsZero() {
+		tags = append(tags, ec2Types.Tag{
+			Key:   aws.String(CopiedAtTag),
+			Value: aws.String(ami.CopiedAt.Format(time.RFC3339)),
+		})
+	}
+
+	return tags
+}
+
+// copiedAtOf returns when a copy was made, from its lineage tag or from its creation date for copies that were made
+// before the tag existed
+func copiedAtOf(image *ec2Types.Image) time.Time {
+	for _, tag := range image.Tags {
+		if aws.ToString(tag.Key) != CopiedAtTag {
+			continue
+		}
+
+		copiedAt, err := time.Parse(time.RFC3339, aws.ToString(tag.Value))
+
+		if err == nil {
+			return copiedAt
+		}
+	}
+
+	creationDate, _ := time.Parse(time.RFC3339, aws.ToString(image.CreationDate))
+
+	return creationDate
+}
+
+// findCopies returns the copies of the AMI in a region. Copies are found by their lineage tags, or by name for copies
+// that were made before the lineage tags existed.
+func (ami *Ami) findCopies(region string) ([]ec2Types.Image, error) {
+	return ami.findCopiesForAccount(*ConfigManager.defaultAccountID, region)
+}
+
+// findDeepCopies returns the deep copies of the AMI that an account owns in a region. Unlike the copies in this account,
+// they are only found by their lineage tags, as other AMI's in the account can have the same name.
+func (ami *Ami) findDeepCopies(account string, region string) ([]ec2Types.Image, error) {
+	log.Debugf("Looking for deep copies of AMI %s in account %s, region %s", ami.SourceAmiID, account, region)
+
+	return describeOwnImages(getEC2ServiceForAccountAndRegion(account, region), []ec2Types.Filter{
+		{
+			Name:   aws.String("tag:" + SourceAmiIDTag),
+			Values: []string{ami.SourceAmiID},
+		},
+		{
+			Name:   aws.String("tag:" + SourceAccountTag),
+			Values: []string{*ConfigManager.defaultAccountID},
+		},
+	})
+}
+
+// findCopiesForAccount returns the copies of the AMI in a region that are owned by the account
+func (ami *Ami) findCopiesForAccount(account string, region string) ([]ec2Types.Image, error) {
+	log.Debugf("Looking for copies of AMI %s in account %s, region %s", ami.SourceAmiID, account, region)
+	ec2svc := getEC2ServiceForAccountAndRegion(account, region)
+
+	images, err := describeOwnImages(ec2svc, []ec2Types.Filter{
+		{
+			Name:   aws.String("tag:" + SourceAmiIDTag),
+			Values: []string{ami.SourceAmiID},
+		},
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(images) == 0 && ami.SourceAmiName != "" {
+		images, err = describeOwnImages(ec2svc, []ec2Types.Filter{
+			{
+				Name:   aws.String("name"),
+				Values: []string{ami.SourceAmiName},
+			},
+		})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// the source AMI is not a copy of itself
+	copies := make([]ec2Types.Image, 0, len(images))
+	for _, image := range images {
+		if *image.ImageId != ami.SourceAmiID {
+			copies = append(copies, image)
+		}
+	}
+
+	log.Debugf("Found %d copies in region %s", len(copies), region)
+
+	return copies, nil
+}
+
+// findLineageForAccount returns the copies of the AMI in a region that the account can use: the copies shared with
+// the account and the deep copies it owns. The lineage tags are only visible to the account that owns a copy, so the
+// shared copies are looked up by the ID's of the copies in the default account.
+func (ami *Ami) findLineageForAccount(account string, region string) ([]ec2Types.Image, error) {
+	if account == *ConfigManager.defaultAccountID {
+		return ami.findCopies(region)
+	}
+
+	copies, err := ami.findCopies(region)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(copies)+1)
+	for _, image := range copies {
+		ids = append(ids, *image.ImageId)
+	}
+
+	if region == ami.SourceRegion {
+		ids = append(ids, ami.SourceAmiID)
+	}
+
+	var images []ec2Types.Image
+
+	if len(ids) > 0 {
+		images, err = describeExecutableImages(getEC2ServiceForAccountAndRegion(account, region), []ec2Types.Filter{
+			{
+				Name:   aws.String("image-id"),
+				Values: ids,
+			},
+		})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	owned, err := ami.findCopiesForAccount(account, region)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return append(images, owned...), nil
+}
+
+func describeOwnImages(ec2svc *ec2.Client, filters []ec2Types.Filter) ([]ec2Types.Image, error) {
+	describeImagesInput := ec2.DescribeImagesInput{
+		Owners:  []string{"self"},
+		Filters: filters,
+	}
+
+	var images []ec2Types.Image
+
+	paginator := ec2.NewDescribeImagesPaginator(ec2svc, &describeImagesInput)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.Background())
+
+		if err != nil {
+			return nil, err
+		}
+
+		images = append(images, page.Images...)
+	}
+
+	return images, nil
+}
+
+// describeExecutableImages returns the images matching the filters that are shared with the account
+func describeExecutableImages(ec2svc *ec2.Client, filters []ec2Types.Filter) ([]ec2Types.Image, error) {
+	describeImagesInput := ec2.DescribeImagesInput{
+		ExecutableUsers: []string{"self"},
+		Filters:         filters,
+	}
+
+	var images []ec2Types.Image
+
+	paginator := ec2.NewDescribeImagesPaginator(ec2svc, &describeImagesInput)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.Background())
+
+		if err != nil {
+			return nil, err
+		}
+
+		images = append(images, page.Images...)
+	}
+
+	return images, nil
+}
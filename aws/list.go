@@ -0,0 +1,224 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// AmiInfo describes a single AMI in the inventory
+type AmiInfo struct {
+	AmiID           string   `json:"amiId"`
+	Name            string   `json:"name"`
+	Region          string   `json:"region"`
+	Account         string   `json:"account"`
+	CreationDate    string   `json:"creationDate"`
+	State           string   `json:"state"`
+	DeprecationTime string   `json:"deprecationTime,omitempty"`
+	Encrypted       bool     `json:"encrypted"`
+	SharedWith      []string `json:"sharedWith"`
+	SnapshotSizes   []int32  `json:"snapshotSizes"`
+}
+
+// ListLineage lists the AMI and its copies in every region and account of the ConfigManager
+func (ami *Ami) ListLineage(regions []string) ([]AmiInfo, error) {
+	err := ami.fetchMetadata()
+
+	if err != nil {
+		return nil, err
+	}
+
+	source, err := newAmiInfo(*ConfigManager.defaultAccountID, ami.SourceRegion, ami.AWSImage)
+
+	if err != nil {
+		return nil, err
+	}
+
+	infos, err := listForAccountsAndRegions(regions, ami.findLineageForAccount)
+
+	if err != nil {
+		return nil, err
+	}
+
+	infos = append(infos, *source)
+	sortAmiInfos(infos)
+
+	return infos, nil
+}
+
+// ListAmis lists the AMI's matching the filters in every region and account of the ConfigManager.
+// Filters are formatted as name=value, e.g. tag:Env=dev.
+func ListAmis(regions []string, filters []string) ([]AmiInfo, error) {
+	ec2Filters, err := parseFilters(filters)
+
+	if err != nil {
+		return nil, err
+	}
+
+	infos, err := listForAccountsAndRegions(regions, func(account string, region string) ([]ec2Types.Image, error) {
+		return describeOwnImages(getEC2ServiceForAccountAndRegion(account, region), ec2Filters)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	sortAmiInfos(infos)
+
+	return infos, nil
+}
+
+// listForAccountsAndRegions concurrently describes the images returned by find for every account and region
+func listForAccountsAndRegions(regions []string, find func(account string, region string) ([]ec2Types.Image, error)) ([]AmiInfo, error) {
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		infos []AmiInfo
+		errs  []error
+	)
+
+	accounts, err := ConfigManager.withCredentials(ConfigManager.getAllAccounts())
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, account := range accounts {
+		for _, region := range regions {
+			wg.Add(1)
+			go func(account string, region string) {
+				defer wg.Done()
+
+				found, err := listForAccountAndRegion(account, region, find)
+
+				mu.Lock()
+				defer mu.Unlock()
+
+				if err != nil {
+					errs = append(errs, fmt.Errorf("account %s, region %s: %w", account, region, err))
+					return
+				}
+
+				infos = append(infos, found...)
+			}(account, region)
+		}
+	}
+
+	wg.Wait()
+
+	return infos, errors.Join(errs...)
+}
+
+func listForAccountAndRegion(account string, region string, find func(account string, region string) ([]ec2Types.Image, error)) ([]AmiInfo, error) {
+	images, err := find(account, region)
+
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]AmiInfo, 0, len(images))
+	for i := range images {
+		info, err := newAmiInfo(account, region, &images[i])
+
+		if err != nil {
+			return nil, err
+		}
+
+		infos = append(infos, *info)
+	}
+
+	log.Debugf("Found %d AMI's in account %s, region %s", len(infos), account, region)
+
+	return infos, nil
+}
+
+func newAmiInfo(account string, region string, image *ec2Types.Image) (*AmiInfo, error) {
+	info := &AmiInfo{
+		AmiID:           aws.ToString(image.ImageId),
+		Name:            aws.ToString(image.Name),
+		Region:          region,
+		Account:         account,
+		CreationDate:    aws.ToString(image.CreationDate),
+		State:           string(image.State),
+		DeprecationTime: aws.ToString(image.DeprecationTime),
+		Encrypted:       isEncrypted(image),
+		SharedWith:      []string{},
+		SnapshotSizes:   []int32{},
+	}
+
+	for _, mapping := range image.BlockDeviceMappings {
+		if mapping.Ebs == nil {
+			continue
+		}
+
+		info.SnapshotSizes = append(info.SnapshotSizes, aws.ToInt32(mapping.Ebs.VolumeSize))
+	}
+
+	// only the owner can describe the launch permissions, e.g. not of the copies that are shared with an account
+	owner := aws.ToString(image.OwnerId)
+	if owner != account && !ConfigManager.isConfiguredAccount(owner) {
+		log.Debugf("AMI %s in account %s is owned by %s, not listing the accounts it is shared with", info.AmiID, account, owner)
+		return info, nil
+	}
+
+	sharedWith, err := getSharedWith(getEC2ServiceForAccountAndRegion(owner, region), info.AmiID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	info.SharedWith = sharedWith
+
+	return info, nil
+}
+
+// getSharedWith returns the accounts, organizations and organizational units the image is shared with
+func getSharedWith(ec2Service *ec2.Client, imageID string) ([]string, error) {
+	describeImageAttributeInput := &ec2.DescribeImageAttributeInput{
+		ImageId:   aws.String(imageID),
+		Attribute: ec2Types.ImageAttributeNameLaunchPermission,
+	}
+
+	attribute, err := ec2Service.DescribeImageAttribute(context.Background(), describeImageAttributeInput)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sharedWith := make([]string, 0, len(attribute.LaunchPermissions))
+	for _, permission := range attribute.LaunchPermissions {
+		switch {
+		case permission.UserId != nil:
+			sharedWith = append(sharedWith, *permission.UserId)
+		case permission.OrganizationArn != nil:
+			sharedWith = append(sharedWith, *permission.OrganizationArn)
+		case permission.OrganizationalUnitArn != nil:
+			sharedWith = append(sharedWith, *permission.OrganizationalUnitArn)
+		case permission.Group == ec2Types.PermissionGroupAll:
+			sharedWith = append(sharedWith, "public")
+		}
+	}
+
+	return sharedWith, nil
+}
+
+func sortAmiInfos(infos []AmiInfo) {
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Account != infos[j].Account {
+			return infos[i].Account < infos[j].Account
+		}
+
+		if infos[i].Region != infos[j].Region {
+			return infos[i].Region < infos[j].Region
+		}
+
+		return infos[i].CreationDate > infos[j].CreationDate
+	})
+}
@@ -0,0 +1,172 @@
+package aws
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"text/template"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+const (
+	maxDescriptionLength int = 255
+)
+
+var (
+	// the characters EC2 allows in the name of an AMI
+	amiNamePattern = regexp.MustCompile(`^[a-zA-Z0-9()\[\] ./\-'@_]{3,128}$`)
+
+	// the environment variables the git SHA is read from, in order of preference
+	gitSHAEnvVars = []string{"GIT_SHA", "GIT_COMMIT", "GITHUB_SHA", "CI_COMMIT_SHA"}
+)
+
+// Naming renders the name and description of a copy from Go templates, which are executed with NameTemplateData
+type Naming struct {
+	Name        *template.Template
+	Description *template.Template
+}
+
+// NameTemplateData is available in the name and description templates
+type NameTemplateData struct {
+	SourceAmiID   string
+	SourceAmiName string
+	SourceRegion  string
+	Region        string
+	Date          string
+	GitSHA        string
+}
+
+// NewNaming parses the name and description templates. An empty name template keeps the name of the source AMI and an
+// empty description template leaves the description empty.
+func NewNaming(nameTemplate string, descriptionTemplate string) (*Naming, error) {
+	naming := &Naming{}
+
+	if nameTemplate != "" {
+		tmpl, err := template.New("name").Option("missingkey=error").Parse(nameTemplate)
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid name template: %w", err)
+		}
+
+		naming.Name = tmpl
+	}
+
+	if descriptionTemplate != "" {
+		tmpl, err := template.New("description").Option("missingkey=error").Parse(descriptionTemplate)
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid description template: %w", err)
+		}
+
+		naming.Description = tmpl
+	}
+
+	return naming, nil
+}
+
+// nameAndDescriptionFor returns the validated name and description of the copy in a region
+func (ami *Ami) nameAndDescriptionFor(region string) (string, string, error) {
+	name := ami.SourceAmiName
+	description := ""
+
+	if ami.Naming == nil {
+		return name, description, ValidateAmiName(name)
+	}
+
+	data := ami.nameTemplateData(region, ami.CopiedAt)
+
+	if ami.Naming.Name != nil {
+		rendered, err := render(ami.Naming.Name, data)
+
+		if err != nil {
+			return "", "", err
+		}
+
+		name = rendered
+	}
+
+	if ami.Naming.Description != nil {
+		rendered, err := render(ami.Naming.Description, data)
+
+		if err != nil {
+			return "", "", err
+		}
+
+		description = rendered
+	}
+
+	if err := ValidateAmiName(name); err != nil {
+		return "", "", err
+	}
+
+	if len(description) > maxDescriptionLength {
+		return "", "", fmt.Errorf("the description for region %s is longer than %d characters", region, maxDescriptionLength)
+	}
+
+	return name, description, nil
+}
+
+// expectedName returns the name copy gave to an image in a region. The source AMI keeps its own name and the name of a
+// copy is rendered with the date it was copied. The git SHA is read from the environment, like when it was copied.
+func (ami *Ami) expectedName(image *ec2Types.Image, region string) (string, error) {
+	if aws.ToString(image.ImageId) == ami.SourceAmiID || ami.Naming == nil || ami.Naming.Name == nil {
+		return ami.SourceAmiName, nil
+	}
+
+	return render(ami.Naming.Name, ami.nameTemplateData(region, copiedAtOf(image)))
+}
+
+func (ami *Ami) nameTemplateData(region string, copiedAt time.Time) NameTemplateData {
+	return NameTemplateData{
+		SourceAmiID:   ami.SourceAmiID,
+		SourceAmiName: ami.SourceAmiName,
+		SourceRegion:  ami.SourceRegion,
+		Region:        region,
+		Date:          copiedAt.Format("2006-01-02"),
+		GitSHA:        gitSHA(),
+	}
+}
+
+// ValidateAmiName checks the name against the constraints of EC2: 3 to 128 letters, numbers, spaces or ( ) [ ] . / - ' @ _
+func ValidateAmiName(name string) error {
+	if !amiNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid AMI name %q, it must be 3 to 128 characters long and can only contain letters, numbers, spaces and ( ) [ ] . / - ' @ _", name)
+	}
+
+	return nil
+}
+
+func render(tmpl *template.Template, data interface{}) (string, error) {
+	var out bytes.Buffer
+
+	err := tmpl.Execute(&out, data)
+
+	if err != nil {
+		return "", err
+	}
+
+	return out.String(), nil
+}
+
+func gitSHA() string {
+	for _, envVar := range gitSHAEnvVars {
+		if sha := os.Getenv(envVar); sha != "" {
+			return sha
+		}
+	}
+
+	return ""
+}
+
+// optionalString returns nil for an empty string, so optional fields are left out of requests
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+
+	return aws.String(s)
+}
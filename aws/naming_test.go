@@ -0,0 +1,87 @@
+package aws
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+func TestValidateAmiName(t *testing.T) {
+	tests := []struct {
+		name    string
+		amiName string
+		wantErr bool
+	}{
+		{name: "simple", amiName: "web-server"},
+		{name: "all allowed characters", amiName: "web (prod) [v1.2] ./-'@_ 2019"},
+		{name: "minimum length", amiName: "abc"},
+		{name: "maximum length", amiName: strings.Repeat("a", 128)},
+		{name: "empty", amiName: "", wantErr: true},
+		{name: "too short", amiName: "ab", wantErr: true},
+		{name: "too long", amiName: strings.Repeat("a", 129), wantErr: true},
+		{name: "colon", amiName: "web:1.2", wantErr: true},
+		{name: "comma", amiName: "web,1.2", wantErr: true},
+		{name: "newline", amiName: "web\n1.2", wantErr: true},
+		{name: "non-ASCII letter", amiName: "wéb-server", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateAmiName(tt.amiName)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateAmiName(%q) error = %v, wantErr %v", tt.amiName, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExpectedName(t *testing.T) {
+	t.Setenv("GIT_SHA", "abc123")
+
+	source := &ec2Types.Image{ImageId: aws.String("ami-0123456789abcdef0")}
+	copied := &ec2Types.Image{
+		ImageId: aws.String("ami-0fedcba9876543210"),
+		Tags:    []ec2Types.Tag{{Key: aws.String(CopiedAtTag), Value: aws.String("2019-04-01T12:00:00Z")}},
+	}
+
+	tests := []struct {
+		name         string
+		nameTemplate string
+		image        *ec2Types.Image
+		want         string
+	}{
+		{name: "copy without template", image: copied, want: "web-1.2"},
+		{name: "copy with template", nameTemplate: "{{.SourceAmiName}}-{{.Region}}-{{.Date}}-{{.GitSHA}}", image: copied, want: "web-1.2-eu-central-1-2019-04-01-abc123"},
+		{name: "source with template", nameTemplate: "{{.SourceAmiName}}-{{.Region}}", image: source, want: "web-1.2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			naming, err := NewNaming(tt.nameTemplate, "")
+
+			if err != nil {
+				t.Fatalf("NewNaming() error = %v", err)
+			}
+
+			ami := &Ami{
+				SourceAmiID:   "ami-0123456789abcdef0",
+				SourceAmiName: "web-1.2",
+				SourceRegion:  "eu-west-1",
+				Naming:        naming,
+			}
+
+			got, err := ami.expectedName(tt.image, "eu-central-1")
+
+			if err != nil {
+				t.Fatalf("expectedName() error = %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("expectedName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/organizations"
+	orgTypes "github.com/aws/aws-sdk-go-v2/service/organizations/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// OrgAccountFilter selects the active accounts of the organization. Empty fields don't filter.
+type OrgAccountFilter struct {
+	// OrganizationalUnits are OU ID's, e.g. ou-ab12-34cd56ef, or paths from the root, e.g. /Workloads/Prod. Accounts in
+	// nested OU's are included.
+	OrganizationalUnits []string
+	// Tags the accounts must have
+	Tags map[string]string
+	// Exclude are account ID's that are never selected
+	Exclude []string
+}
+
+// NewOrgAccountFilter creates a filter from OU's, tags formatted as key=value and account ID's to exclude
+func NewOrgAccountFilter(organizationalUnits []string, tags []string, exclude []string) (*OrgAccountFilter, error) {
+	filter := &OrgAccountFilter{
+		OrganizationalUnits: organizationalUnits,
+		Tags:                make(map[string]string),
+		Exclude:             exclude,
+	}
+
+	for _, tag := range tags {
+		key, value, found := strings.Cut(tag, "=")
+
+		if !found || key == "" {
+			return nil, fmt.Errorf("invalid account tag %q, expected key=value", tag)
+		}
+
+		filter.Tags[key] = value
+	}
+
+	return filter, nil
+}
+
+// DiscoverAccounts returns the ID's of the active accounts in the organization that match the filter. The default
+// account must be the management account or a delegated administrator of the organization.
+func (cm *ConfigurationManager) DiscoverAccounts(filter *OrgAccountFilter) ([]string, error) {
+	cm.endpoints.warnDefaultEndpoint(serviceOrganizations)
+
+	orgService := organizations.NewFromConfig(cm.defaultConfig)
+
+	var (
+		candidates []orgTypes.Account
+		err        error
+	)
+
+	if len(filter.OrganizationalUnits) == 0 {
+		candidates, err = listAllAccounts(orgService)
+
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		for _, ou := range filter.OrganizationalUnits {
+			ouID, err := resolveOrganizationalUnit(orgService, ou)
+
+			if err != nil {
+				return nil, err
+			}
+
+			accounts, err := listAccountsInOrganizationalUnit(orgService, ouID)
+
+			if err != nil {
+				return nil, err
+			}
+
+			candidates = append(candidates, accounts...)
+		}
+	}
+
+	// the AMI's are owned by the default account, so it is never a target
+	excluded := toSet(append([]string{*cm.defaultAccountID}, filter.Exclude...))
+	selected := make(map[string]bool)
+
+	for _, account := range candidates {
+		id := aws.ToString(account.Id)
+
+		if selected[id] || excluded[id] {
+			continue
+		}
+
+		if account.Status != orgTypes.AccountStatusActive {
+			log.Debugf("Skipping account %s, its status is %s", id, account.Status)
+			continue
+		}
+
+		if len(filter.Tags) > 0 {
+			matches, err := accountHasTags(orgService, id, filter.Tags)
+
+			if err != nil {
+				return nil, err
+			}
+
+			if !matches {
+				continue
+			}
+		}
+
+		selected[id] = true
+	}
+
+	accounts := make([]string, 0, len(selected))
+	for id := range selected {
+		accounts = append(accounts, id)
+	}
+
+	sort.Strings(accounts)
+
+	log.Infof("Found %d accounts in the organization: %s", len(accounts), strings.Join(accounts, ", "))
+
+	return accounts, nil
+}
+
+func listAllAccounts(orgService *organizations.Client) ([]orgTypes.Account, error) {
+	var accounts []orgTypes.Account
+
+	paginator := organizations.NewListAccountsPaginator(orgService, &organizations.ListAccountsInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.Background())
+
+		if err != nil {
+			return nil, err
+		}
+
+		accounts = append(accounts, page.Accounts...)
+	}
+
+	return accounts, nil
+}
+
+// listAccountsInOrganizationalUnit returns the accounts in an OU and in the OU's nested in it
+func listAccountsInOrganizationalUnit(orgService *organizations.Client, ouID string) ([]orgTypes.Account, error) {
+	var accounts []orgTypes.Account
+
+	accountsPaginator := organizations.NewListAccountsForParentPaginator(orgService, &organizations.ListAccountsForParentInput{
+		ParentId: aws.String(ouID),
+	})
+	for accountsPaginator.HasMorePages() {
+		page, err := accountsPaginator.NextPage(context.Background())
+
+		if err != nil {
+			return nil, err
+		}
+
+		accounts = append(accounts, page.Accounts...)
+	}
+
+	children, err := listOrganizationalUnits(orgService, ouID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, child := range children {
+		childAccounts, err := listAccountsInOrganizationalUnit(orgService, aws.ToString(child.Id))
+
+		if err != nil {
+			return nil, err
+		}
+
+		accounts = append(accounts, childAccounts...)
+	}
+
+	return accounts, nil
+}
+
+func listOrganizationalUnits(orgService *organizations.Client, parentID string) ([]orgTypes.OrganizationalUnit, error) {
+	var units []orgTypes.OrganizationalUnit
+
+	paginator := organizations.NewListOrganizationalUnitsForParentPaginator(orgService, &organizations.ListOrganizationalUnitsForParentInput{
+		ParentId: aws.String(parentID),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.Background())
+
+		if err != nil {
+			return nil, err
+		}
+
+		units = append(units, page.OrganizationalUnits...)
+	}
+
+	return units, nil
+}
+
+// resolveOrganizationalUnit returns the ID of an OU given as ID, or as path of OU names from the root
+func resolveOrganizationalUnit(orgService *organizations.Client, ou string) (string, error) {
+	if strings.HasPrefix(ou, "ou-") {
+		return ou, nil
+	}
+
+	roots, err := orgService.ListRoots(context.Background(), &organizations.ListRootsInput{})
+
+	if err != nil {
+		return "", err
+	}
+
+	if len(roots.Roots) == 0 {
+		return "", fmt.Errorf("the organization has no root")
+	}
+
+	parentID := aws.ToString(roots.Roots[0].Id)
+
+	for _, name := range strings.Split(strings.Trim(ou, "/"), "/") {
+		if name == "" {
+			continue
+		}
+
+		units, err := listOrganizationalUnits(orgService, parentID)
+
+		if err != nil {
+			return "", err
+		}
+
+		found := false
+		for _, unit := range units {
+			if aws.ToString(unit.Name) == name {
+				parentID = aws.ToString(unit.Id)
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return "", fmt.Errorf("organizational unit %s not found in %s", name, ou)
+		}
+	}
+
+	log.Debugf("Organizational unit %s has ID %s", ou, parentID)
+
+	return parentID, nil
+}
+
+func accountHasTags(orgService *organizations.Client, accountID string, tags map[string]string) (bool, error) {
+	accountTags := make(map[string]string)
+
+	paginator := organizations.NewListTagsForResourcePaginator(orgService, &organizations.ListTagsForResourceInput{
+		ResourceId: aws.String(accountID),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.Background())
+
+		if err != nil {
+			return false, err
+		}
+
+		for _, tag := range page.Tags {
+			accountTags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+		}
+	}
+
+	for key, value := range tags {
+		if accountTags[key] != value {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
@@ -0,0 +1,66 @@
+package aws
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	awsArn "github.com/aws/aws-sdk-go-v2/aws/arn"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	PartitionAWS      string = "aws"
+	PartitionAWSUSGov string = "aws-us-gov"
+	PartitionAWSCN    string = "aws-cn"
+)
+
+var regionPattern = regexp.MustCompile(`^[a-z]{2}(-[a-z]+)+-[0-9]+$`)
+
+// partitionForRegion returns the partition a region belongs to, e.g. aws-us-gov for us-gov-west-1
+func partitionForRegion(region string) string {
+	switch {
+	case strings.HasPrefix(region, "us-gov-"):
+		return PartitionAWSUSGov
+	case strings.HasPrefix(region, "cn-"):
+		return PartitionAWSCN
+	default:
+		return PartitionAWS
+	}
+}
+
+// detectPartition returns the partition of the caller identity ARN, or of the region when the ARN can't be parsed
+func detectPartition(callerArn *string, region string) string {
+	if callerArn != nil {
+		parsed, err := awsArn.Parse(*callerArn)
+
+		if err == nil && parsed.Partition != "" {
+			return parsed.Partition
+		}
+	}
+
+	log.Debugf("Unable to detect the partition from the caller identity, using the partition of region %s", region)
+
+	return partitionForRegion(region)
+}
+
+// GetPartition returns the partition of the default account, e.g. aws, aws-us-gov or aws-cn
+func (cm *ConfigurationManager) GetPartition() string {
+	return cm.partition
+}
+
+// ValidateRegions checks that the regions are valid region names in the partition of the default account. Regions of
+// another partition can't be reached with the same credentials.
+func (cm *ConfigurationManager) ValidateRegions(regions []string) error {
+	for _, region := range regions {
+		if !regionPattern.MatchString(region) {
+			return fmt.Errorf("invalid region %q", region)
+		}
+
+		if partition := partitionForRegion(region); partition != cm.partition {
+			return fmt.Errorf("region %s is in partition %s, but the credentials are for partition %s", region, partition, cm.partition)
+		}
+	}
+
+	return nil
+}
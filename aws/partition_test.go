@@ -0,0 +1,104 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestPartitionForRegion(t *testing.T) {
+	tests := []struct {
+		region string
+		want   string
+	}{
+		{region: "eu-west-1", want: PartitionAWS},
+		{region: "us-east-1", want: PartitionAWS},
+		{region: "ap-southeast-4", want: PartitionAWS},
+		{region: "us-gov-west-1", want: PartitionAWSUSGov},
+		{region: "us-gov-east-1", want: PartitionAWSUSGov},
+		{region: "cn-north-1", want: PartitionAWSCN},
+		{region: "cn-northwest-1", want: PartitionAWSCN},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.region, func(t *testing.T) {
+			if got := partitionForRegion(tt.region); got != tt.want {
+				t.Errorf("partitionForRegion(%s) = %s, want %s", tt.region, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectPartition(t *testing.T) {
+	tests := []struct {
+		name      string
+		callerArn *string
+		region    string
+		want      string
+	}{
+		{name: "from the caller", callerArn: aws.String("arn:aws-us-gov:iam::123456789012:user/ci"), region: "eu-west-1", want: PartitionAWSUSGov},
+		{name: "without caller", region: "cn-north-1", want: PartitionAWSCN},
+		{name: "invalid caller", callerArn: aws.String("not-an-arn"), region: "us-gov-west-1", want: PartitionAWSUSGov},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectPartition(tt.callerArn, tt.region); got != tt.want {
+				t.Errorf("detectPartition() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateRegions(t *testing.T) {
+	tests := []struct {
+		name      string
+		partition string
+		regions   []string
+		wantErr   bool
+	}{
+		{name: "commercial", partition: PartitionAWS, regions: []string{"eu-west-1", "us-east-1"}},
+		{name: "GovCloud", partition: PartitionAWSUSGov, regions: []string{"us-gov-west-1"}},
+		{name: "no regions", partition: PartitionAWS},
+		{name: "other partition", partition: PartitionAWS, regions: []string{"eu-west-1", "cn-north-1"}, wantErr: true},
+		{name: "commercial region in GovCloud", partition: PartitionAWSUSGov, regions: []string{"us-east-1"}, wantErr: true},
+		{name: "invalid name", partition: PartitionAWS, regions: []string{"eu-west"}, wantErr: true},
+		{name: "upper case", partition: PartitionAWS, regions: []string{"EU-WEST-1"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cm := &ConfigurationManager{partition: tt.partition}
+
+			err := cm.ValidateRegions(tt.regions)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateRegions(%v) error = %v, wantErr %v", tt.regions, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRoleArnForPartition(t *testing.T) {
+	tests := []struct {
+		name      string
+		partition string
+		want      string
+	}{
+		{name: "default partition", want: "arn:aws:iam::123456789012:role/terraform"},
+		{name: "GovCloud", partition: PartitionAWSUSGov, want: "arn:aws-us-gov:iam::123456789012:role/terraform"},
+		{name: "China", partition: PartitionAWSCN, want: "arn:aws-cn:iam::123456789012:role/terraform"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var accountsConfig *AccountsConfig
+
+			got := accountsConfig.forAccount("123456789012", "terraform", tt.partition).roleArnFor("123456789012")
+
+			if got != tt.want {
+				t.Errorf("roleArnFor() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,476 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	kmsTypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/aws/smithy-go"
+	log "github.com/sirupsen/logrus"
+)
+
+// The names of the preflight checks
+const (
+	CheckCredentials          string = "credentials"
+	CheckAssumeRole           string = "assume-role"
+	CheckDescribeImages       string = "describe-images"
+	CheckCopyImage            string = "copy-image"
+	CheckModifyImageAttribute string = "modify-image-attribute"
+	CheckCreateTags           string = "create-tags"
+	CheckDeleteTags           string = "delete-tags"
+	CheckDeregisterImage      string = "deregister-image"
+	CheckSourceKmsKey         string = "source-kms-key"
+	CheckTargetKmsKey         string = "target-kms-key"
+)
+
+// The statuses of a preflight check. A check is skipped when there is nothing to check it against yet.
+const (
+	CheckOK      string = "ok"
+	CheckDenied  string = "denied"
+	CheckError   string = "error"
+	CheckSkipped string = "skipped"
+)
+
+// The commands the preflight checks the actions of
+const (
+	PreflightCopy    string = "copy"
+	PreflightCleanup string = "cleanup"
+	PreflightRemove  string = "remove"
+	PreflightSync    string = "sync"
+	PreflightUnshare string = "unshare"
+	PreflightPromote string = "promote"
+)
+
+// PreflightCommands are the commands the preflight can check, in the order they are documented
+var PreflightCommands = []string{PreflightCopy, PreflightCleanup, PreflightRemove, PreflightSync, PreflightUnshare, PreflightPromote}
+
+// Check is the result of a preflight check in an account and region. The region is empty for checks of the account.
+type Check struct {
+	Account string `json:"account"`
+	Region  string `json:"region,omitempty"`
+	Name    string `json:"check"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// Preflight verifies that the roles can be assumed in every account, and that the actions of a command are allowed in
+// every account and region, with DryRun requests that don't change anything. The actions on an AMI are checked
+// against the source AMI or one of its copies in the region, so they are skipped in the regions without one.
+type Preflight struct {
+	// Command is the command whose actions are checked, one of PreflightCommands
+	Command string
+	// SourceAmiID is the AMI the command acts on. Without it, the checks that need an AMI are skipped.
+	SourceAmiID  string
+	SourceRegion string
+	// TargetKmsKeyID is the key the deep copies are encrypted with in the accounts, when set
+	TargetKmsKeyID string
+}
+
+func NewPreflight(command string, sourceAmiID string, sourceRegion string, targetKmsKeyID string) (*Preflight, error) {
+	if !slices.Contains(PreflightCommands, command) {
+		return nil, fmt.Errorf("unknown command %q, expected one of %s", command, strings.Join(PreflightCommands, ", "))
+	}
+
+	return &Preflight{
+		Command:        command,
+		SourceAmiID:    sourceAmiID,
+		SourceRegion:   sourceRegion,
+		TargetKmsKeyID: targetKmsKeyID,
+	}, nil
+}
+
+// Run runs the checks in every account and region concurrently, and returns the results sorted by account, region and
+// check
+func (preflight *Preflight) Run(regions []string) []Check {
+	var sourceImage *ec2Types.Image
+
+	if preflight.SourceAmiID != "" {
+		ami := NewAmi(preflight.SourceAmiID)
+		ami.SourceRegion = preflight.SourceRegion
+
+		if err := ami.fetchMetadata(); err != nil {
+			log.Warnf("Unable to describe AMI %s, skipping the checks of the source KMS key: %s", preflight.SourceAmiID, err)
+		} else {
+			sourceImage = ami.AWSImage
+		}
+	}
+
+	imagesPerRegion := preflight.findImages(regions, sourceImage)
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		checks []Check
+	)
+
+	report := func(check Check) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		checks = append(checks, check)
+	}
+
+	for _, account := range ConfigManager.getAllAccounts() {
+		wg.Add(1)
+		go func(account string) {
+			defer wg.Done()
+
+			isDefault := account == *ConfigManager.defaultAccountID
+			name := CheckAssumeRole
+			if isDefault {
+				name = CheckCredentials
+			}
+
+			conf := ConfigManager.getConfigurationForAccount(account)
+			_, err := conf.Credentials.Retrieve(context.Background())
+			report(newCheck(account, "", name, err))
+
+			// the other checks can't succeed without credentials
+			if err != nil {
+				return
+			}
+
+			var regionsWg sync.WaitGroup
+			for _, region := range regions {
+				regionsWg.Add(1)
+				go func(region string) {
+					defer regionsWg.Done()
+
+					for _, check := range preflight.checkRegion(account, region, isDefault, sourceImage, imagesPerRegion[region]) {
+						report(check)
+					}
+				}(region)
+			}
+
+			regionsWg.Wait()
+		}(account)
+	}
+
+	wg.Wait()
+
+	sort.Slice(checks, func(i, j int) bool {
+		if checks[i].Account != checks[j].Account {
+			return checks[i].Account < checks[j].Account
+		}
+
+		if checks[i].Region != checks[j].Region {
+			return checks[i].Region < checks[j].Region
+		}
+
+		return checks[i].Name < checks[j].Name
+	})
+
+	return checks
+}
+
+// findImages returns the AMI per region to check the actions against: the source AMI in its own region, unless it is
+// shared by another account, and otherwise a copy of it that exists already
+func (preflight *Preflight) findImages(regions []string, sourceImage *ec2Types.Image) map[string]string {
+	imagesPerRegion := make(map[string]string)
+
+	if preflight.SourceAmiID == "" {
+		return imagesPerRegion
+	}
+
+	ami := NewAmi(preflight.SourceAmiID)
+	ami.SourceRegion = preflight.SourceRegion
+	ami.AWSImage = sourceImage
+	if sourceImage != nil {
+		ami.SourceAmiName = aws.ToString(sourceImage.Name)
+	}
+
+	for _, region := range regions {
+		if region == preflight.SourceRegion && !ami.isShared() {
+			imagesPerRegion[region] = preflight.SourceAmiID
+			continue
+		}
+
+		copies, err := ami.findCopies(region)
+
+		if err != nil {
+			log.Warnf("Unable to find the copies of AMI %s in region %s: %s", preflight.SourceAmiID, region, err)
+			continue
+		}
+
+		if len(copies) > 0 {
+			imagesPerRegion[region] = *copies[0].ImageId
+		}
+	}
+
+	return imagesPerRegion
+}
+
+// checkRegion runs the checks of the command in an account and region. The actions on an AMI are checked against
+// imageID, which is owned by the default account.
+func (preflight *Preflight) checkRegion(account string, region string, isDefault bool, sourceImage *ec2Types.Image, imageID string) []Check {
+	ec2svc := getEC2ServiceForAccountAndRegion(account, region)
+	ctx := context.Background()
+
+	_, err := ec2svc.DescribeImages(ctx, &ec2.DescribeImagesInput{
+		Owners: []string{"self"},
+		DryRun: aws.Bool(true),
+	})
+	checks := []Check{newDryRunCheck(account, region, CheckDescribeImages, err)}
+
+	if preflight.SourceAmiID == "" {
+		return checks
+	}
+
+	// a shared source AMI is copied into its own region as well
+	isShared := sourceImage != nil && aws.ToString(sourceImage.OwnerId) != *ConfigManager.defaultAccountID
+
+	if preflight.Command == PreflightCopy && isDefault && (region != preflight.SourceRegion || isShared) {
+		_, err = ec2svc.CopyImage(ctx, &ec2.CopyImageInput{
+			Name:          aws.String("ami-manager-preflight"),
+			SourceImageId: aws.String(preflight.SourceAmiID),
+			SourceRegion:  aws.String(preflight.SourceRegion),
+			DryRun:        aws.Bool(true),
+		})
+		checks = append(checks, newDryRunCheck(account, region, CheckCopyImage, err))
+	}
+
+	for _, name := range preflight.imageChecks(isDefault) {
+		if imageID == "" {
+			checks = append(checks, Check{
+				Account: account,
+				Region:  region,
+				Name:    name,
+				Status:  CheckSkipped,
+				Message: fmt.Sprintf("there is no copy of AMI %s in this region to check against yet", preflight.SourceAmiID),
+			})
+			continue
+		}
+
+		checks = append(checks, newDryRunCheck(account, region, name, preflight.dryRun(ec2svc, name, imageID)))
+	}
+
+	if preflight.Command != PreflightCopy {
+		return checks
+	}
+
+	if isDefault && region == preflight.SourceRegion && sourceImage != nil && isEncrypted(sourceImage) {
+		keyIDs, err := snapshotKmsKeyIDs(sourceImage, region)
+
+		if err != nil {
+			checks = append(checks, Check{
+				Account: account,
+				Region:  region,
+				Name:    CheckSourceKmsKey,
+				Status:  CheckSkipped,
+				Message: fmt.Sprintf("unable to describe the snapshots of AMI %s: %s", preflight.SourceAmiID, err),
+			})
+		}
+
+		for _, keyID := range keyIDs {
+			checks = append(checks, newCheck(account, region, CheckSourceKmsKey, checkKmsKey(account, region, keyID)))
+		}
+	}
+
+	if !isDefault && preflight.TargetKmsKeyID != "" {
+		checks = append(checks, newCheck(account, region, CheckTargetKmsKey, checkKmsKey(account, region, preflight.TargetKmsKeyID)))
+	}
+
+	return checks
+}
+
+// imageChecks returns the checks of the actions the command takes on an image in an account
+func (preflight *Preflight) imageChecks(isDefault bool) []string {
+	switch preflight.Command {
+	case PreflightCopy:
+		if isDefault {
+			return []string{CheckModifyImageAttribute, CheckCreateTags}
+		}
+
+		return []string{CheckCreateTags}
+	case PreflightSync:
+		if isDefault {
+			return []string{CheckModifyImageAttribute, CheckCreateTags, CheckDeleteTags}
+		}
+
+		return []string{CheckCreateTags, CheckDeleteTags}
+	case PreflightUnshare:
+		if isDefault {
+			return []string{CheckModifyImageAttribute}
+		}
+
+		return []string{CheckDeleteTags}
+	case PreflightPromote:
+		return []string{CheckCreateTags, CheckDeleteTags}
+	default:
+		if isDefault {
+			return []string{CheckModifyImageAttribute, CheckDeregisterImage}
+		}
+
+		return []string{CheckDeleteTags}
+	}
+}
+
+// dryRun runs the action of a check on an image as a DryRun request
+func (preflight *Preflight) dryRun(ec2svc *ec2.Client, name string, imageID string) error {
+	ctx := context.Background()
+	tags := []ec2Types.Tag{{Key: aws.String("ami-manager:preflight"), Value: aws.String("true")}}
+
+	var err error
+
+	switch name {
+	case CheckModifyImageAttribute:
+		permissions := &ec2Types.LaunchPermissionModifications{}
+		if preflight.Command == PreflightCopy || preflight.Command == PreflightSync {
+			permissions.Add = createLaunchPermissionsForOwners(ConfigManager.getAccounts())
+		} else {
+			permissions.Remove = createLaunchPermissionsForOwners(ConfigManager.getAccounts())
+		}
+
+		_, err = ec2svc.ModifyImageAttribute(ctx, &ec2.ModifyImageAttributeInput{
+			ImageId:          aws.String(imageID),
+			LaunchPermission: permissions,
+			DryRun:           aws.Bool(true),
+		})
+	case CheckCreateTags:
+		_, err = ec2svc.CreateTags(ctx, &ec2.CreateTagsInput{
+			Resources: []string{imageID},
+			Tags:      tags,
+			DryRun:    aws.Bool(true),
+		})
+	case CheckDeleteTags:
+		_, err = ec2svc.DeleteTags(ctx, &ec2.DeleteTagsInput{
+			Resources: []string{imageID},
+			Tags:      tags,
+			DryRun:    aws.Bool(true),
+		})
+	case CheckDeregisterImage:
+		_, err = ec2svc.DeregisterImage(ctx, &ec2.DeregisterImageInput{
+			ImageId: aws.String(imageID),
+			DryRun:  aws.Bool(true),
+		})
+	}
+
+	return err
+}
+
+// checkKmsKey checks that the key is enabled and can be used for encryption in the account and region. DescribeKey
+// doesn't prove that the key can be used, so generating a data key and creating a grant, which EBS does to copy
+// snapshots, are checked with DryRun requests.
+func checkKmsKey(account string, region string, keyID string) error {
+	ConfigManager.endpoints.warnDefaultEndpoint(serviceKMS)
+
+	kmsService := kms.NewFromConfig(ConfigManager.getConfigurationForAccountAndRegion(account, region))
+
+	output, err := kmsService.DescribeKey(context.Background(), &kms.DescribeKeyInput{
+		KeyId: aws.String(keyID),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if output.KeyMetadata.KeyState != kmsTypes.KeyStateEnabled {
+		return fmt.Errorf("key %s is %s", keyID, output.KeyMetadata.KeyState)
+	}
+
+	if output.KeyMetadata.KeyUsage != kmsTypes.KeyUsageTypeEncryptDecrypt {
+		return fmt.Errorf("key %s can't be used to encrypt, its usage is %s", keyID, output.KeyMetadata.KeyUsage)
+	}
+
+	_, err = kmsService.GenerateDataKeyWithoutPlaintext(context.Background(), &kms.GenerateDataKeyWithoutPlaintextInput{
+		KeyId:   aws.String(keyID),
+		KeySpec: kmsTypes.DataKeySpecAes256,
+		DryRun:  aws.Bool(true),
+	})
+
+	if err != nil && !isDryRunOperation(err) {
+		return fmt.Errorf("unable to generate a data key with key %s: %w", keyID, err)
+	}
+
+	_, err = kmsService.CreateGrant(context.Background(), &kms.CreateGrantInput{
+		KeyId:            aws.String(keyID),
+		GranteePrincipal: aws.String("arn:" + ConfigManager.partition + ":iam::" + account + ":root"),
+		Operations:       []kmsTypes.GrantOperation{kmsTypes.GrantOperationDecrypt},
+		DryRun:           aws.Bool(true),
+	})
+
+	if err != nil && !isDryRunOperation(err) {
+		return fmt.Errorf("unable to create a grant for key %s: %w", keyID, err)
+	}
+
+	return nil
+}
+
+// isDryRunOperation returns true when the error of a DryRun request means the request would have succeeded. EC2 and
+// KMS use different error codes for it.
+func isDryRunOperation(err error) bool {
+	var apiErr smithy.APIError
+
+	return errors.As(err, &apiErr) && (apiErr.ErrorCode() == "DryRunOperation" || apiErr.ErrorCode() == "DryRunOperationException")
+}
+
+// newDryRunCheck converts the error of a DryRun request to a check. A DryRunOperation error means the request would
+// have succeeded.
+func newDryRunCheck(account string, region string, name string, err error) Check {
+	if isDryRunOperation(err) {
+		err = nil
+	}
+
+	return newCheck(account, region, name, err)
+}
+
+func newCheck(account string, region string, name string, err error) Check {
+	check := Check{
+		Account: account,
+		Region:  region,
+		Name:    name,
+		Status:  CheckOK,
+	}
+
+	if err == nil {
+		return check
+	}
+
+	check.Message = err.Error()
+	check.Status = CheckError
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "UnauthorizedOperation", "AccessDenied", "AccessDeniedException":
+			check.Status = CheckDenied
+		}
+	}
+
+	return check
+}
+
+// FailedChecks returns the checks that did not succeed
+func FailedChecks(checks []Check) []Check {
+	var failed []Check
+
+	for _, check := range checks {
+		if check.Status == CheckDenied || check.Status == CheckError {
+			failed = append(failed, check)
+		}
+	}
+
+	return failed
+}
+
+// SkippedChecks returns the checks that were skipped
+func SkippedChecks(checks []Check) []Check {
+	var skipped []Check
+
+	for _, check := range checks {
+		if check.Status == CheckSkipped {
+			skipped = append(skipped, check)
+		}
+	}
+
+	return skipped
+}
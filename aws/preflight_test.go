@@ -0,0 +1,124 @@
+package aws
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/aws/smithy-go"
+)
+
+func TestPreflightImageChecks(t *testing.T) {
+	tests := []struct {
+		name      string
+		command   string
+		isDefault bool
+		want      []string
+	}{
+		{
+			name:      "copy in the default account",
+			command:   PreflightCopy,
+			isDefault: true,
+			want:      []string{CheckModifyImageAttribute, CheckCreateTags},
+		},
+		{
+			name:    "copy in another account",
+			command: PreflightCopy,
+			want:    []string{CheckCreateTags},
+		},
+		{
+			name:      "sync in the default account",
+			command:   PreflightSync,
+			isDefault: true,
+			want:      []string{CheckModifyImageAttribute, CheckCreateTags, CheckDeleteTags},
+		},
+		{
+			name:      "unshare in the default account",
+			command:   PreflightUnshare,
+			isDefault: true,
+			want:      []string{CheckModifyImageAttribute},
+		},
+		{
+			name:    "unshare in another account",
+			command: PreflightUnshare,
+			want:    []string{CheckDeleteTags},
+		},
+		{
+			name:      "promote in the default account",
+			command:   PreflightPromote,
+			isDefault: true,
+			want:      []string{CheckCreateTags, CheckDeleteTags},
+		},
+		{
+			name:      "remove in the default account",
+			command:   PreflightRemove,
+			isDefault: true,
+			want:      []string{CheckModifyImageAttribute, CheckDeregisterImage},
+		},
+		{
+			name:    "cleanup in another account",
+			command: PreflightCleanup,
+			want:    []string{CheckDeleteTags},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			preflight, err := NewPreflight(tt.command, "", "", "")
+
+			if err != nil {
+				t.Fatalf("NewPreflight() error = %v", err)
+			}
+
+			if got := preflight.imageChecks(tt.isDefault); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("imageChecks() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := NewPreflight("rollback", "", "", ""); err == nil {
+		t.Errorf("NewPreflight() of an unknown command should fail")
+	}
+}
+
+func TestIsDryRunOperation(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "ec2",
+			err:  &smithy.GenericAPIError{Code: "DryRunOperation"},
+			want: true,
+		},
+		{
+			name: "kms",
+			err:  &smithy.GenericAPIError{Code: "DryRunOperationException"},
+			want: true,
+		},
+		{
+			name: "denied",
+			err:  &smithy.GenericAPIError{Code: "AccessDeniedException"},
+			want: false,
+		},
+		{
+			name: "other error",
+			err:  errors.New("connection refused"),
+			want: false,
+		},
+		{
+			name: "no error",
+			err:  nil,
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isDryRunOperation(tt.err); got != tt.want {
+				t.Errorf("isDryRunOperation() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
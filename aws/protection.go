@@ -0,0 +1,105 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultProtectionTag string = "ami-manager:protected=true"
+)
+
+var (
+	// ErrProtected is returned when an AMI is protected from being removed
+	ErrProtected = errors.New("AMI is protected")
+
+	// Protection is the policy that is checked before an AMI is removed
+	Protection = &ProtectionPolicy{
+		TagKey:   "ami-manager:protected",
+		TagValue: "true",
+	}
+)
+
+// ProtectionPolicy protects AMI's with the protection tag or with EC2 deregistration protection from being removed,
+// unless the removal is forced.
+type ProtectionPolicy struct {
+	TagKey   string
+	TagValue string
+	Force    bool
+}
+
+// NewProtectionPolicy creates a policy for a protection tag formatted as key=value. An empty tag only protects AMI's
+// with deregistration protection.
+func NewProtectionPolicy(tag string, force bool) (*ProtectionPolicy, error) {
+	policy := &ProtectionPolicy{Force: force}
+
+	if tag == "" {
+		return policy, nil
+	}
+
+	key, value, found := strings.Cut(tag, "=")
+
+	if !found || key == "" {
+		return nil, fmt.Errorf("invalid protection tag %q, expected key=value", tag)
+	}
+
+	policy.TagKey = key
+	policy.TagValue = value
+
+	return policy, nil
+}
+
+// verify returns ErrProtected when the image is protected and the removal is not forced
+func (p *ProtectionPolicy) verify(image *ec2Types.Image) error {
+	if p.Force {
+		return nil
+	}
+
+	if p.hasProtectionTag(image) {
+		return fmt.Errorf("%w: %s has tag %s=%s, use --force to remove it anyway", ErrProtected, *image.ImageId, p.TagKey, p.TagValue)
+	}
+
+	if hasDeregistrationProtection(image) {
+		return fmt.Errorf("%w: %s has deregistration protection, use --force to remove it anyway", ErrProtected, *image.ImageId)
+	}
+
+	return nil
+}
+
+func (p *ProtectionPolicy) hasProtectionTag(image *ec2Types.Image) bool {
+	if p.TagKey == "" {
+		return false
+	}
+
+	tag, ok := convertTagSliceToMap(image.Tags)[p.TagKey]
+
+	return ok && tag.Value != nil && *tag.Value == p.TagValue
+}
+
+func hasDeregistrationProtection(image *ec2Types.Image) bool {
+	// the value is either disabled, enabled-with-cooldown or enabled-without-cooldown
+	return image.DeregistrationProtection != nil && strings.HasPrefix(*image.DeregistrationProtection, "enabled")
+}
+
+// disableDeregistrationProtection lifts the deregistration protection of a forced removal
+func disableDeregistrationProtection(image *ec2Types.Image, ec2Service *ec2.Client) error {
+	if !hasDeregistrationProtection(image) {
+		return nil
+	}
+
+	log.Warnf("Disabling deregistration protection of AMI %s", *image.ImageId)
+
+	input := &ec2.DisableImageDeregistrationProtectionInput{
+		ImageId: image.ImageId,
+	}
+
+	_, err := ec2Service.DisableImageDeregistrationProtection(context.Background(), input)
+
+	return err
+}
@@ -0,0 +1,151 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// RegionsAll selects every region that is enabled for the account
+	RegionsAll string = "all"
+	// RegionsOptedIn selects the opt-in regions the account has opted in to
+	RegionsOptedIn string = "opted-in"
+
+	// a region prefixed with this is excluded, e.g. all,-us-east-1
+	excludeRegionPrefix string = "-"
+
+	optInNotRequired string = "opt-in-not-required"
+	optedIn          string = "opted-in"
+)
+
+// ResolveRegions expands all and opted-in to the regions of the account, removes the excluded regions, and checks that
+// the other regions exist and are enabled for the account
+func (cm *ConfigurationManager) ResolveRegions(regions []string) ([]string, error) {
+	if len(regions) == 0 {
+		return regions, nil
+	}
+
+	optInStatus, err := describeRegions()
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to describe the regions: %w", err)
+	}
+
+	resolved, err := resolveRegions(regions, optInStatus, *cm.defaultAccountID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debugf("Using regions %s", strings.Join(resolved, ", "))
+
+	return resolved, nil
+}
+
+// resolveRegions resolves the regions against the opt-in status of every region of the account, by region name
+func resolveRegions(regions []string, optInStatus map[string]string, account string) ([]string, error) {
+	var (
+		included []string
+		excluded = make(map[string]bool)
+	)
+
+	for _, region := range regions {
+		if strings.HasPrefix(region, excludeRegionPrefix) {
+			excluded[strings.TrimPrefix(region, excludeRegionPrefix)] = true
+		} else {
+			included = append(included, region)
+		}
+	}
+
+	for region := range excluded {
+		if _, ok := optInStatus[region]; !ok {
+			return nil, fmt.Errorf("unknown region %s in the excluded regions", region)
+		}
+	}
+
+	selected := make(map[string]bool)
+
+	for _, region := range included {
+		switch region {
+		case RegionsAll, RegionsOptedIn:
+			for name, status := range optInStatus {
+				if status == optedIn || (region == RegionsAll && status == optInNotRequired) {
+					selected[name] = true
+				}
+			}
+		default:
+			status, ok := optInStatus[region]
+
+			if !ok {
+				return nil, fmt.Errorf("unknown region %s, valid regions are %s", region, strings.Join(sortedKeys(optInStatus), ", "))
+			}
+
+			if status != optedIn && status != optInNotRequired {
+				return nil, fmt.Errorf("region %s is not enabled for account %s, its opt-in status is %s", region, account, status)
+			}
+
+			selected[region] = true
+		}
+	}
+
+	resolved := make([]string, 0, len(selected))
+	for region := range selected {
+		if !excluded[region] {
+			resolved = append(resolved, region)
+		}
+	}
+
+	sort.Strings(resolved)
+
+	if len(resolved) == 0 {
+		return nil, fmt.Errorf("no regions left in %s", strings.Join(regions, ","))
+	}
+
+	return resolved, nil
+}
+
+// describeRegions returns the opt-in status of every region, by region name
+func describeRegions() (map[string]string, error) {
+	ec2svc := getEC2ServiceForAccountAndRegion(*ConfigManager.defaultAccountID, ConfigManager.GetDefaultRegion())
+
+	result, err := ec2svc.DescribeRegions(context.Background(), &ec2.DescribeRegionsInput{
+		AllRegions: aws.Bool(true),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	optInStatus := make(map[string]string, len(result.Regions))
+	for _, region := range result.Regions {
+		optInStatus[aws.ToString(region.RegionName)] = aws.ToString(region.OptInStatus)
+	}
+
+	return optInStatus, nil
+}
+
+// getEnabledRegions returns the regions that are enabled for the default account
+func getEnabledRegions() ([]string, error) {
+	optInStatus, err := describeRegions()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var regions []string
+	for region, status := range optInStatus {
+		if status == optedIn || status == optInNotRequired {
+			regions = append(regions, region)
+		}
+	}
+
+	sort.Strings(regions)
+
+	return regions, nil
+}
@@ -0,0 +1,98 @@
+package aws
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResolveRegions(t *testing.T) {
+	optInStatus := map[string]string{
+		"eu-west-1":    optInNotRequired,
+		"eu-central-1": optInNotRequired,
+		"us-east-1":    optInNotRequired,
+		"eu-south-1":   optedIn,
+		"af-south-1":   "not-opted-in",
+	}
+
+	tests := []struct {
+		name    string
+		regions []string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "regions",
+			regions: []string{"us-east-1", "eu-west-1"},
+			want:    []string{"eu-west-1", "us-east-1"},
+		},
+		{
+			name:    "opted-in region",
+			regions: []string{"eu-south-1"},
+			want:    []string{"eu-south-1"},
+		},
+		{
+			name:    "duplicates",
+			regions: []string{"eu-west-1", "eu-west-1"},
+			want:    []string{"eu-west-1"},
+		},
+		{
+			name:    "all",
+			regions: []string{RegionsAll},
+			want:    []string{"eu-central-1", "eu-south-1", "eu-west-1", "us-east-1"},
+		},
+		{
+			name:    "opted-in",
+			regions: []string{RegionsOptedIn},
+			want:    []string{"eu-south-1"},
+		},
+		{
+			name:    "all with exclusions",
+			regions: []string{RegionsAll, "-us-east-1", "-eu-south-1"},
+			want:    []string{"eu-central-1", "eu-west-1"},
+		},
+		{
+			name:    "opted-in and a region",
+			regions: []string{RegionsOptedIn, "eu-west-1"},
+			want:    []string{"eu-south-1", "eu-west-1"},
+		},
+		{
+			name:    "excluding a region that is not selected",
+			regions: []string{"eu-west-1", "-af-south-1"},
+			want:    []string{"eu-west-1"},
+		},
+		{
+			name:    "unknown region",
+			regions: []string{"eu-west-9"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown excluded region",
+			regions: []string{RegionsAll, "-eu-west-9"},
+			wantErr: true,
+		},
+		{
+			name:    "region that is not enabled",
+			regions: []string{"af-south-1"},
+			wantErr: true,
+		},
+		{
+			name:    "every region excluded",
+			regions: []string{"eu-west-1", "-eu-west-1"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveRegions(tt.regions, optInStatus, "123456789012")
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveRegions(%v) error = %v, wantErr %v", tt.regions, err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resolveRegions(%v) = %v, want %v", tt.regions, got, tt.want)
+			}
+		})
+	}
+}
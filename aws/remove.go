@@ -0,0 +1,286 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
+	log "github.com/sirupsen/logrus"
+)
+
+// RemoveResult is the outcome of removing a single AMI
+type RemoveResult struct {
+	AmiID  string
+	Region string
+	Err    error
+}
+
+// RemoveAmis removes the AMI's from a region, with at most concurrency removals running at the same time.
+// When allCopies is set, the copies in copyRegions are removed as well. When sourceAccount is set, the AMI's are shared
+// by that account and only their copies are removed.
+func RemoveAmis(amiIDs []string, region string, sourceAccount string, concurrency int, allCopies bool, copyRegions []string) []RemoveResult {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	results := make([]RemoveResult, len(amiIDs))
+	semaphore := make(chan struct{}, concurrency)
+
+	var wg sync.WaitGroup
+
+	for i, amiID := range amiIDs {
+		wg.Add(1)
+		go func(i int, amiID string) {
+			defer wg.Done()
+
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			ami := NewAmi(amiID)
+			ami.SourceRegion = region
+			ami.SourceAccount = sourceAccount
+
+			var err error
+			if allCopies {
+				err = ami.RemoveAllCopies(copyRegions)
+			} else {
+				err = ami.RemoveAmi()
+			}
+
+			if err != nil {
+				log.Errorf("Unable to remove AMI %s: %s", amiID, err)
+			}
+
+			results[i] = RemoveResult{
+				AmiID:  amiID,
+				Region: region,
+				Err:    err,
+			}
+		}(i, amiID)
+	}
+
+	wg.Wait()
+
+	return results
+}
+
+// DescribeAmis returns a tab-separated line with the region, ID, name and creation date of every AMI, together with
+// the ID's of the AMI's that don't exist in the region
+func DescribeAmis(amiIDs []string, region string) ([]string, []string, error) {
+	ec2svc := getEC2ServiceForAccountAndRegion(*ConfigManager.defaultAccountID, region)
+
+	images, err := describeImagesByID(ec2svc, amiIDs)
+
+	// a single unknown ID fails the whole request, so the AMI's are looked up one by one to find the missing ones
+	if isInvalidAmiID(err) {
+		images = nil
+
+		for _, amiID := range amiIDs {
+			found, err := describeImagesByID(ec2svc, []string{amiID})
+
+			if isInvalidAmiID(err) {
+				continue
+			}
+
+			if err != nil {
+				return nil, nil, err
+			}
+
+			images = append(images, found...)
+		}
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	found := make(map[string]bool, len(images))
+	lines := make([]string, 0, len(images))
+
+	for _, image := range images {
+		found[*image.ImageId] = true
+		lines = append(lines, describeImage(region, &image))
+	}
+
+	var missing []string
+	for _, amiID := range amiIDs {
+		if !found[amiID] {
+			missing = append(missing, amiID)
+		}
+	}
+
+	return lines, missing, nil
+}
+
+// DescribeCopies returns a tab-separated line for every copy of the AMI's in copyRegions, like DescribeAmis does,
+// followed by the AMI it is a copy of and the accounts it is shared with, and for every deep copy in the accounts. When
+// sourceAccount is set, the AMI's are
+// shared by that account and their copies in region are described as well.
+func DescribeCopies(amiIDs []string, region string, sourceAccount string, copyRegions []string) ([]string, error) {
+	var lines []string
+
+	shared := ""
+	if accounts := ConfigManager.getAccounts(); len(accounts) > 0 {
+		shared = ", shared with " + strings.Join(accounts, ", ")
+	}
+
+	deepCopyAccounts, err := ConfigManager.withCredentials(ConfigManager.getAccounts())
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, amiID := range amiIDs {
+		ami := NewAmi(amiID)
+		ami.SourceRegion = region
+		ami.SourceAccount = sourceAccount
+
+		err := ami.fetchMetadata()
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, copyRegion := range copyRegions {
+			for _, account := range deepCopyAccounts {
+				if account == *ConfigManager.defaultAccountID {
+					continue
+				}
+
+				deepCopies, err := ami.findDeepCopies(account, copyRegion)
+
+				if err != nil {
+					return nil, fmt.Errorf("account %s, region %s: %w", account, copyRegion, err)
+				}
+
+				for _, image := range deepCopies {
+					lines = append(lines, fmt.Sprintf("%s\tdeep copy of %s in account %s", describeImage(copyRegion, &image), amiID, account))
+				}
+			}
+
+			if copyRegion == region && !ami.isShared() {
+				continue
+			}
+
+			copies, err := ami.findCopies(copyRegion)
+
+			if err != nil {
+				return nil, fmt.Errorf("region %s: %w", copyRegion, err)
+			}
+
+			for _, image := range copies {
+				lines = append(lines, fmt.Sprintf("%s\tcopy of %s%s", describeImage(copyRegion, &image), amiID, shared))
+			}
+		}
+	}
+
+	return lines, nil
+}
+
+func describeImagesByID(ec2svc *ec2.Client, amiIDs []string) ([]ec2Types.Image, error) {
+	var images []ec2Types.Image
+
+	paginator := ec2.NewDescribeImagesPaginator(ec2svc, &ec2.DescribeImagesInput{
+		ImageIds: amiIDs,
+	})
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.Background())
+
+		if err != nil {
+			return nil, err
+		}
+
+		images = append(images, page.Images...)
+	}
+
+	return images, nil
+}
+
+// isInvalidAmiID returns true when the error is caused by an AMI ID that doesn't exist or is malformed
+func isInvalidAmiID(err error) bool {
+	var apiErr smithy.APIError
+
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	return apiErr.ErrorCode() == "InvalidAMIID.NotFound" || apiErr.ErrorCode() == "InvalidAMIID.Malformed"
+}
+
+// FindAmiIDs returns the ID's of the AMI's owned by the current account in a region that match all filters and are
+// older than olderThan. A zero olderThan matches AMI's of any age. Filters are formatted as name=value, e.g. tag:Env=dev.
+func FindAmiIDs(region string, filters []string, olderThan time.Duration) ([]string, error) {
+	ec2Filters, err := parseFilters(filters)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ec2svc := getEC2ServiceForAccountAndRegion(*ConfigManager.defaultAccountID, region)
+
+	images, err := describeOwnImages(ec2svc, ec2Filters)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var amiIDs []string
+	for _, image := range images {
+		if olderThan > 0 {
+			old, err := isOlderThan(image, olderThan)
+
+			if err != nil {
+				return nil, err
+			}
+
+			if !old {
+				continue
+			}
+		}
+
+		amiIDs = append(amiIDs, *image.ImageId)
+	}
+
+	log.Debugf("Found %d AMI's in region %s matching the filters", len(amiIDs), region)
+
+	return amiIDs, nil
+}
+
+func isOlderThan(image ec2Types.Image, age time.Duration) (bool, error) {
+	if image.CreationDate == nil {
+		return false, nil
+	}
+
+	creationDate, err := time.Parse(time.RFC3339, *image.CreationDate)
+
+	if err != nil {
+		return false, err
+	}
+
+	return time.Since(creationDate) > age, nil
+}
+
+func parseFilters(filters []string) ([]ec2Types.Filter, error) {
+	ec2Filters := make([]ec2Types.Filter, 0, len(filters))
+
+	for _, filter := range filters {
+		name, value, found := strings.Cut(filter, "=")
+
+		if !found || name == "" {
+			return nil, fmt.Errorf("invalid filter %q, expected name=value, e.g. tag:Env=dev", filter)
+		}
+
+		ec2Filters = append(ec2Filters, ec2Types.Filter{
+			Name:   aws.String(name),
+			Values: []string{value},
+		})
+	}
+
+	return ec2Filters, nil
+}
@@ -0,0 +1,66 @@
+package aws
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+func TestParseFilters(t *testing.T) {
+	tests := []struct {
+		name    string
+		filters []string
+		want    []ec2Types.Filter
+		wantErr bool
+	}{
+		{
+			name:    "no filters",
+			filters: nil,
+			want:    []ec2Types.Filter{},
+		},
+		{
+			name:    "tag filter",
+			filters: []string{"tag:Env=dev"},
+			want:    []ec2Types.Filter{{Name: aws.String("tag:Env"), Values: []string{"dev"}}},
+		},
+		{
+			name:    "value containing an equals sign",
+			filters: []string{"name=a=b", "state=available"},
+			want: []ec2Types.Filter{
+				{Name: aws.String("name"), Values: []string{"a=b"}},
+				{Name: aws.String("state"), Values: []string{"available"}},
+			},
+		},
+		{
+			name:    "empty value",
+			filters: []string{"tag:Env="},
+			want:    []ec2Types.Filter{{Name: aws.String("tag:Env"), Values: []string{""}}},
+		},
+		{
+			name:    "missing equals sign",
+			filters: []string{"tag:Env"},
+			wantErr: true,
+		},
+		{
+			name:    "missing name",
+			filters: []string{"=dev"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseFilters(tt.filters)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseFilters(%q) error = %v, wantErr %v", tt.filters, err, tt.wantErr)
+			}
+
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseFilters(%q) = %v, want %v", tt.filters, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsArn "github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/imagebuilder"
+	"github.com/aws/smithy-go"
+	log "github.com/sirupsen/logrus"
+)
+
+// AmiSource is the AMI ID and region of a source AMI
+type AmiSource struct {
+	AmiID  string
+	Region string
+}
+
+// packerManifest is the part of the manifest written by the Packer manifest post-processor that is used here
+type packerManifest struct {
+	Builds []struct {
+		Name          string `json:"name"`
+		BuilderType   string `json:"builder_type"`
+		ArtifactID    string `json:"artifact_id"`
+		PackerRunUUID string `json:"packer_run_uuid"`
+	} `json:"builds"`
+	LastRunUUID string `json:"last_run_uuid"`
+}
+
+// ResolvePackerManifest returns the AMI of the last build in a Packer manifest. The artifact ID of an Amazon builder is
+// formatted as region:ami-id, with multiple AMI's separated by commas. The first one is used.
+func ResolvePackerManifest(path string) (*AmiSource, error) {
+	content, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest packerManifest
+
+	err = json.Unmarshal(content, &manifest)
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse Packer manifest %s: %w", path, err)
+	}
+
+	// the builds of the last run are at the end of the manifest
+	for i := len(manifest.Builds) - 1; i >= 0; i-- {
+		build := manifest.Builds[i]
+
+		if manifest.LastRunUUID != "" && build.PackerRunUUID != manifest.LastRunUUID {
+			continue
+		}
+
+		artifacts := strings.Split(build.ArtifactID, ",")
+		region, amiID, found := strings.Cut(artifacts[0], ":")
+
+		if !found || !strings.HasPrefix(amiID, "ami-") {
+			log.Debugf("Skipping build %s, artifact %s is not an AMI", build.Name, build.ArtifactID)
+			continue
+		}
+
+		if len(artifacts) > 1 {
+			log.Warnf("Build %s created %d AMI's, using %s in region %s", build.Name, len(artifacts), amiID, region)
+		}
+
+		log.Infof("Using AMI %s in region %s from build %s of Packer manifest %s", amiID, region, build.Name, path)
+
+		return &AmiSource{
+			AmiID:  amiID,
+			Region: region,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("no AMI found in the last run of Packer manifest %s", path)
+}
+
+// ResolveImageBuilderImage returns the AMI created by an EC2 Image Builder image build version, preferring the AMI in
+// the region of the image.
+func ResolveImageBuilderImage(imageArn string) (*AmiSource, error) {
+	parsed, err := awsArn.Parse(imageArn)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid Image Builder image ARN %s: %w", imageArn, err)
+	}
+
+	ConfigManager.endpoints.warnDefaultEndpoint(serviceImageBuilder)
+
+	imageBuilderService := imagebuilder.NewFromConfig(ConfigManager.getConfigurationForDefaultAccountAndRegion(parsed.Region))
+
+	output, err := imageBuilderService.GetImage(context.Background(), &imagebuilder.GetImageInput{
+		ImageBuildVersionArn: aws.String(imageArn),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if output.Image == nil || output.Image.OutputResources == nil || len(output.Image.OutputResources.Amis) == 0 {
+		return nil, fmt.Errorf("image %s has no AMI's", imageArn)
+	}
+
+	amis := output.Image.OutputResources.Amis
+	ami := amis[0]
+	for _, candidate := range amis {
+		if aws.ToString(candidate.Region) == parsed.Region {
+			ami = candidate
+			break
+		}
+	}
+
+	log.Infof("Using AMI %s in region %s from Image Builder image %s", aws.ToString(ami.Image), aws.ToString(ami.Region), imageArn)
+
+	return &AmiSource{
+		AmiID:  aws.ToString(ami.Image),
+		Region: aws.ToString(ami.Region),
+	}, nil
+}
+
+// LocateAmi returns the region of an AMI owned by or shared with the default account. The preferred region is tried
+// first, then every region that is enabled for the account.
+func LocateAmi(amiID string, preferredRegion string) (string, error) {
+	if preferredRegion != "" {
+		found, err := amiExists(amiID, preferredRegion)
+
+		if err != nil {
+			return "", err
+		}
+
+		if found {
+			return preferredRegion, nil
+		}
+	}
+
+	log.Infof("AMI %s not found in region %s, looking for it in the other enabled regions", amiID, preferredRegion)
+
+	regions, err := getEnabledRegions()
+
+	if err != nil {
+		return "", err
+	}
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		found []string
+		errs  []error
+	)
+
+	for _, region := range regions {
+		if region == preferredRegion {
+			continue
+		}
+
+		wg.Add(1)
+		go func(region string) {
+			defer wg.Done()
+
+			exists, err := amiExists(amiID, region)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				errs = append(errs, fmt.Errorf("region %s: %w", region, err))
+				return
+			}
+
+			if exists {
+				found = append(found, region)
+			}
+		}(region)
+	}
+
+	wg.Wait()
+
+	// AMI ID's are unique across regions
+	if len(found) > 0 {
+		log.Infof("Found AMI %s in region %s", amiID, found[0])
+		return found[0], nil
+	}
+
+	if len(errs) > 0 {
+		return "", errors.Join(errs...)
+	}
+
+	return "", fmt.Errorf("AMI %s not found in any enabled region", amiID)
+}
+
+func amiExists(amiID string, region string) (bool, error) {
+	ec2svc := getEC2ServiceForAccountAndRegion(*ConfigManager.defaultAccountID, region)
+
+	result, err := ec2svc.DescribeImages(context.Background(), &ec2.DescribeImagesInput{
+		ImageIds: []string{amiID},
+	})
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorCode() == "InvalidAMIID.NotFound" {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return len(result.Images) > 0, nil
+}
@@ -0,0 +1,120 @@
+package aws
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestResolvePackerManifest(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		want     *AmiSource
+		wantErr  bool
+	}{
+		{
+			name: "single build",
+			manifest: `{
+				"builds": [
+					{"name": "web", "builder_type": "amazon-ebs", "artifact_id": "eu-west-1:ami-0123456789abcdef0", "packer_run_uuid": "run-1"}
+				],
+				"last_run_uuid": "run-1"
+			}`,
+			want: &AmiSource{AmiID: "ami-0123456789abcdef0", Region: "eu-west-1"},
+		},
+		{
+			name: "last build of the last run",
+			manifest: `{
+				"builds": [
+					{"name": "web", "artifact_id": "eu-west-1:ami-00000000000000001", "packer_run_uuid": "run-1"},
+					{"name": "web", "artifact_id": "eu-west-1:ami-00000000000000002", "packer_run_uuid": "run-2"},
+					{"name": "web", "artifact_id": "eu-west-1:ami-00000000000000003", "packer_run_uuid": "run-1"}
+				],
+				"last_run_uuid": "run-2"
+			}`,
+			want: &AmiSource{AmiID: "ami-00000000000000002", Region: "eu-west-1"},
+		},
+		{
+			name: "without last run",
+			manifest: `{
+				"builds": [
+					{"name": "web", "artifact_id": "eu-west-1:ami-00000000000000001"},
+					{"name": "web", "artifact_id": "us-east-1:ami-00000000000000002"}
+				]
+			}`,
+			want: &AmiSource{AmiID: "ami-00000000000000002", Region: "us-east-1"},
+		},
+		{
+			name: "multiple regions",
+			manifest: `{
+				"builds": [
+					{"name": "web", "artifact_id": "eu-west-1:ami-00000000000000001,us-east-1:ami-00000000000000002"}
+				]
+			}`,
+			want: &AmiSource{AmiID: "ami-00000000000000001", Region: "eu-west-1"},
+		},
+		{
+			name: "skips builds that are not AMI's",
+			manifest: `{
+				"builds": [
+					{"name": "web", "artifact_id": "eu-west-1:ami-00000000000000001", "packer_run_uuid": "run-1"},
+					{"name": "docker", "builder_type": "docker", "artifact_id": "sha256:0123", "packer_run_uuid": "run-1"}
+				],
+				"last_run_uuid": "run-1"
+			}`,
+			want: &AmiSource{AmiID: "ami-00000000000000001", Region: "eu-west-1"},
+		},
+		{
+			name: "no AMI in the last run",
+			manifest: `{
+				"builds": [
+					{"name": "web", "artifact_id": "eu-west-1:ami-00000000000000001", "packer_run_uuid": "run-1"}
+				],
+				"last_run_uuid": "run-2"
+			}`,
+			wantErr: true,
+		},
+		{
+			name:     "no builds",
+			manifest: `{"builds": []}`,
+			wantErr:  true,
+		},
+		{
+			name:     "invalid JSON",
+			manifest: `{"builds": [`,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "manifest.json")
+
+			err := os.WriteFile(path, []byte(tt.manifest), 0o600)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := ResolvePackerManifest(path)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolvePackerManifest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ResolvePackerManifest() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolvePackerManifestMissingFile(t *testing.T) {
+	_, err := ResolvePackerManifest(filepath.Join(t.TempDir(), "missing.json"))
+
+	if err == nil {
+		t.Error("ResolvePackerManifest() error = nil, want an error for a missing file")
+	}
+}
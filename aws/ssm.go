@@ -0,0 +1,147 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"text/template"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmTypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	ssmServices   = make(map[string]map[string]*ssm.Client)
+	ssmServicesMu sync.Mutex
+)
+
+func getSSMServiceForAccountAndRegion(account string, region string) *ssm.Client {
+	log.Debugf("getSSMServiceForAccountAndRegion: account %s, region %s", account, region)
+	ssmServicesMu.Lock()
+	defer ssmServicesMu.Unlock()
+
+	if ssmServices[account] == nil {
+		ssmServices[account] = make(map[string]*ssm.Client)
+	}
+
+	if ssmServices[account][region] == nil {
+		ConfigManager.endpoints.warnDefaultEndpoint(serviceSSM)
+		ssmServices[account][region] = ssm.NewFromConfig(ConfigManager.getConfigurationForAccountAndRegion(account, region))
+	}
+	return ssmServices[account][region]
+}
+
+// SSMPublisher writes the ID's of the copies to an SSM parameter in every region, and optionally in every account
+type SSMPublisher struct {
+	NameTemplate *template.Template
+	Label        string
+	AllAccounts  bool
+}
+
+// SSMTemplateData is available in the parameter name template
+type SSMTemplateData struct {
+	Name        string
+	SourceAmiID string
+	Region      string
+	Account     string
+}
+
+// NewSSMPublisher creates a publisher for a parameter name template, e.g. /ami/{{.Name}}/latest. When label is set, the
+// new parameter version is labeled with it. With allAccounts, the parameter is written in every account.
+func NewSSMPublisher(nameTemplate string, label string, allAccounts bool) (*SSMPublisher, error) {
+	tmpl, err := template.New("parameter").Option("missingkey=error").Parse(nameTemplate)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid SSM parameter template: %w", err)
+	}
+
+	return &SSMPublisher{
+		NameTemplate: tmpl,
+		Label:        label,
+		AllAccounts:  allAccounts,
+	}, nil
+}
+
+// publish writes the ID of the AMI in the region to the parameter. In accounts with a deep copy, the ID of that copy is
+// written instead.
+func (publisher *SSMPublisher) publish(ami *Ami, region string, amiID string, copiesPerAccount map[string]string) error {
+	accounts := []string{*ConfigManager.defaultAccountID}
+	if publisher.AllAccounts {
+		var err error
+		accounts, err = ConfigManager.withCredentials(ConfigManager.getAllAccounts())
+
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, account := range accounts {
+		name, err := render(publisher.NameTemplate, SSMTemplateData{
+			Name:        ami.SourceAmiName,
+			SourceAmiID: ami.SourceAmiID,
+			Region:      region,
+			Account:     account,
+		})
+
+		if err != nil {
+			return err
+		}
+
+		accountAmiID := amiID
+		if copyID, ok := copiesPerAccount[account]; ok {
+			accountAmiID = copyID
+		}
+
+		err = publisher.putParameter(account, region, name, accountAmiID)
+
+		if err != nil {
+			return fmt.Errorf("unable to publish %s to parameter %s in account %s: %w", accountAmiID, name, account, err)
+		}
+	}
+
+	return nil
+}
+
+func (publisher *SSMPublisher) putParameter(account string, region string, name string, amiID string) error {
+	ssmService := getSSMServiceForAccountAndRegion(account, region)
+
+	putParameterInput := &ssm.PutParameterInput{
+		Name:      aws.String(name),
+		Value:     aws.String(amiID),
+		Type:      ssmTypes.ParameterTypeString,
+		DataType:  aws.String("aws:ec2:image"),
+		Overwrite: aws.Bool(true),
+	}
+
+	output, err := ssmService.PutParameter(context.Background(), putParameterInput)
+
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Published AMI %s to parameter %s in account %s, region %s (version %d)", amiID, name, account, region, output.Version)
+
+	if publisher.Label == "" {
+		return nil
+	}
+
+	labelParameterVersionInput := &ssm.LabelParameterVersionInput{
+		Name:             aws.String(name),
+		ParameterVersion: aws.Int64(output.Version),
+		Labels:           []string{publisher.Label},
+	}
+
+	labelOutput, err := ssmService.LabelParameterVersion(context.Background(), labelParameterVersionInput)
+
+	if err != nil {
+		return err
+	}
+
+	if len(labelOutput.InvalidLabels) > 0 {
+		return fmt.Errorf("invalid labels %v", labelOutput.InvalidLabels)
+	}
+
+	return nil
+}
@@ -0,0 +1,242 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// Sync reconciles the launch permissions and tags of the AMI and its existing copies in the regions, without copying.
+// Missing launch permissions are added for the accounts of the ConfigManager and the tags of the source AMI are set in
+// every account. With prune, launch permissions of other accounts and tags that are not on the source AMI are removed.
+func (ami *Ami) Sync(regions []string, prune bool) error {
+	err := ami.fetchMetadata()
+
+	if err != nil {
+		return err
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+
+	for _, region := range regions {
+		wg.Add(1)
+		go func(region string) {
+			defer wg.Done()
+
+			err := ami.syncRegion(region, prune)
+
+			if err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("region %s: %w", region, err))
+				mu.Unlock()
+			}
+		}(region)
+	}
+
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+func (ami *Ami) syncRegion(region string, prune bool) error {
+	var images []ec2Types.Image
+
+	// a shared source AMI is copied into the source region as well
+	if region == ami.SourceRegion && !ami.isShared() {
+		images = []ec2Types.Image{*ami.AWSImage}
+	} else {
+		copies, err := ami.findCopies(region)
+
+		if err != nil {
+			return err
+		}
+
+		if len(copies) == 0 {
+			log.Warnf("No copies of AMI %s found in region %s, use copy first", ami.SourceAmiID, region)
+			return nil
+		}
+
+		images = copies
+	}
+
+	for _, image := range images {
+		relatedAmi := &Ami{
+			SourceAmiID:  *image.ImageId,
+			SourceRegion: region,
+			AWSImage:     &image,
+		}
+
+		// like copy, only the copies are shared with the accounts and never the source AMI itself
+		if relatedAmi.SourceAmiID != ami.SourceAmiID {
+			err := relatedAmi.syncOwners(ConfigManager.getAccounts(), prune)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		err := ami.syncTags(relatedAmi, prune)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// syncOwners adds the launch permissions that are missing for the owners and, with prune, removes the launch
+// permissions of other accounts
+func (ami *Ami) syncOwners(owners []string, prune bool) error {
+	ec2Service := getEC2ServiceForAccountAndRegion(*ConfigManager.defaultAccountID, ami.SourceRegion)
+
+	sharedWith, err := getSharedWith(ec2Service, ami.SourceAmiID)
+
+	if err != nil {
+		return err
+	}
+
+	current := toSet(sharedWith)
+	wanted := toSet(owners)
+
+	var toAdd []string
+	for _, owner := range owners {
+		if owner != *ConfigManager.defaultAccountID && !current[owner] {
+			toAdd = append(toAdd, owner)
+		}
+	}
+
+	if len(toAdd) > 0 {
+		log.Infof("Adding launch permissions for %v to AMI %s", toAdd, ami.SourceAmiID)
+
+		err = ami.setOwners(toAdd)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if !prune {
+		return nil
+	}
+
+	// only accounts are pruned, sharing with organizations or the public is left as is
+	var toRemove []string
+	for _, account := range sharedWith {
+		if isAccountID(account) && !wanted[account] {
+			toRemove = append(toRemove, account)
+		}
+	}
+
+	if len(toRemove) == 0 {
+		return nil
+	}
+
+	log.Infof("Removing launch permissions for %v from AMI %s", toRemove, ami.SourceAmiID)
+
+	modifyImageAttributeInput := &ec2.ModifyImageAttributeInput{
+		ImageId: aws.String(ami.SourceAmiID),
+		LaunchPermission: &ec2Types.LaunchPermissionModifications{
+			Remove: createLaunchPermissionsForOwners(toRemove),
+		},
+	}
+
+	_, err = ec2Service.ModifyImageAttribute(context.Background(), modifyImageAttributeInput)
+
+	return err
+}
+
+// syncTags sets the tags of the source AMI, transformed by the tag rules, on the related AMI in every account and, with
+// prune, removes the other tags. The tags that are managed by AWS or by this tool are never removed.
+func (ami *Ami) syncTags(relatedAmi *Ami, prune bool) error {
+	accounts, err := ConfigManager.withCredentials(ConfigManager.getAllAccounts())
+
+	if err != nil {
+		return err
+	}
+
+	for _, account := range accounts {
+		// the source AMI is where the tags come from
+		if account == *ConfigManager.defaultAccountID && relatedAmi.SourceAmiID == ami.SourceAmiID {
+			continue
+		}
+
+		wantedTags, err := ami.expectedTags(relatedAmi.AWSImage, relatedAmi.SourceRegion, account)
+
+		if err != nil {
+			return err
+		}
+
+		if len(wantedTags) > 0 {
+			err := relatedAmi.setTagsForAccount(account, convertTagMapToSlice(wantedTags))
+
+			if err != nil {
+				return err
+			}
+		}
+
+		if !prune {
+			continue
+		}
+
+		tags, err := describeTagsForAccount(account, relatedAmi.SourceRegion, relatedAmi.SourceAmiID)
+
+		if err != nil {
+			return err
+		}
+
+		var extraTags []ec2Types.Tag
+		for key := range comparableTags(tags) {
+			if _, ok := wantedTags[key]; !ok {
+				extraTags = append(extraTags, ec2Types.Tag{Key: aws.String(key)})
+			}
+		}
+
+		if len(extraTags) > 0 {
+			err = removeTagsForAccount(account, relatedAmi.SourceRegion, relatedAmi.SourceAmiID, extraTags)
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func convertTagMapToSlice(tagMap map[string]string) []ec2Types.Tag {
+	tags := make([]ec2Types.Tag, 0, len(tagMap))
+
+	for _, key := range sortedKeys(tagMap) {
+		tags = append(tags, ec2Types.Tag{
+			Key:   aws.String(key),
+			Value: aws.String(tagMap[key]),
+		})
+	}
+
+	return tags
+}
+
+func isAccountID(s string) bool {
+	if len(s) != 12 {
+		return false
+	}
+
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,256 @@
+package aws
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// ProvenanceTags are added to the copies by TagRules with provenance enabled. Like the lineage tags they are managed by
+// this tool, so verify doesn't compare them and sync never prunes them.
+var ProvenanceTags = map[string]string{
+	"ami-manager:provenance:copied-from":    "{{.SourceAmiID}}",
+	"ami-manager:provenance:source-region":  "{{.SourceRegion}}",
+	"ami-manager:provenance:source-account": "{{.SourceAccount}}",
+	"ami-manager:provenance:copied-at":      "{{.CopiedAt}}",
+}
+
+// TagRules transform the tags of the source AMI before they are set on a copy and in the target accounts.
+// Tags are dropped first, then renamed, and finally added. The values of added tags are Go templates that are executed
+// with TagTemplateData. The provenance tags are added before the other tags, so they can be overridden.
+type TagRules struct {
+	Add        map[string]*template.Template
+	Drop       map[string]bool
+	Rename     map[string]string
+	Provenance map[string]*template.Template
+}
+
+// TagTemplateData is available in the templates of added tags
+type TagTemplateData struct {
+	SourceAmiID   string
+	SourceAmiName string
+	SourceRegion  string
+	SourceAccount string
+	Region        string
+	Account       string
+	CopiedAt      string
+	Tags          map[string]string
+}
+
+// NewTagRules creates tag rules from tags to add formatted as key=template, keys to drop and renames formatted as
+// old=new. With provenance, the ProvenanceTags are added as well.
+func NewTagRules(add []string, drop []string, rename []string, provenance bool) (*TagRules, error) {
+	rules := &TagRules{
+		Add:        make(map[string]*template.Template),
+		Drop:       make(map[string]bool),
+		Rename:     make(map[string]string),
+		Provenance: make(map[string]*template.Template),
+	}
+
+	if provenance {
+		for key, value := range ProvenanceTags {
+			err := addTemplate(rules.Provenance, key, value)
+
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, tag := range add {
+		key, value, found := strings.Cut(tag, "=")
+
+		if !found || key == "" {
+			return nil, fmt.Errorf("invalid tag %q, expected key=value", tag)
+		}
+
+		err := addTemplate(rules.Add, key, value)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, key := range drop {
+		rules.Drop[key] = true
+	}
+
+	for _, tag := range rename {
+		oldKey, newKey, found := strings.Cut(tag, "=")
+
+		if !found || oldKey == "" || newKey == "" {
+			return nil, fmt.Errorf("invalid rename %q, expected old=new", tag)
+		}
+
+		rules.Rename[oldKey] = newKey
+	}
+
+	return rules, nil
+}
+
+func addTemplate(templates map[string]*template.Template, key string, value string) error {
+	tmpl, err := template.New(key).Option("missingkey=error").Parse(value)
+
+	if err != nil {
+		return fmt.Errorf("invalid template for tag %s: %w", key, err)
+	}
+
+	templates[key] = tmpl
+
+	return nil
+}
+
+// withoutProvenance returns the rules without the provenance tags, for the source AMI
+func (rules *TagRules) withoutProvenance() *TagRules {
+	if rules == nil {
+		return nil
+	}
+
+	return &TagRules{
+		Add:    rules.Add,
+		Drop:   rules.Drop,
+		Rename: rules.Rename,
+	}
+}
+
+// Apply transforms the tags. Tags managed by AWS are never copied. An error is returned when a tag is renamed to
+// the key of another tag.
+func (rules *TagRules) Apply(tags []ec2Types.Tag, data TagTemplateData) ([]ec2Types.Tag, error) {
+	result := make(map[string]string)
+	sourceKeys := make(map[string]string)
+
+	for _, tag := range tags {
+		key := aws.ToString(tag.Key)
+
+		if strings.HasPrefix(key, "aws:") {
+			continue
+		}
+
+		if rules != nil && rules.Drop[key] {
+			continue
+		}
+
+		sourceKey := key
+		if rules != nil && rules.Rename[key] != "" {
+			key = rules.Rename[key]
+		}
+
+		if other, ok := sourceKeys[key]; ok {
+			return nil, fmt.Errorf("tags %s and %s would both be named %s, check the renames", other, sourceKey, key)
+		}
+
+		sourceKeys[key] = sourceKey
+		result[key] = aws.ToString(tag.Value)
+	}
+
+	if rules != nil {
+		data.Tags = convertTagSliceToValueMap(tags)
+
+		for _, templates := range []map[string]*template.Template{rules.Provenance, rules.Add} {
+			for key, tmpl := range templates {
+				value, err := render(tmpl, data)
+
+				if err != nil {
+					return nil, fmt.Errorf("unable to render tag %s: %w", key, err)
+				}
+
+				result[key] = value
+			}
+		}
+	}
+
+	return convertTagMapToSlice(result), nil
+}
+
+// keepsSourceTags returns true when the rules don't drop or rename any of the source tags
+func (rules *TagRules) keepsSourceTags() bool {
+	return rules == nil || (len(rules.Drop) == 0 && len(rules.Rename) == 0)
+}
+
+// withoutSourceTags returns the tags that are not on the source AMI with the same value
+func (ami *Ami) withoutSourceTags(tags []ec2Types.Tag) []ec2Types.Tag {
+	var sourceTags map[string]string
+	if ami.SourceAmiTags != nil {
+		sourceTags = convertTagSliceToValueMap(*ami.SourceAmiTags)
+	}
+
+	result := make([]ec2Types.Tag, 0, len(tags))
+	for _, tag := range tags {
+		value, ok := sourceTags[aws.ToString(tag.Key)]
+
+		if !ok || value != aws.ToString(tag.Value) {
+			result = append(result, tag)
+		}
+	}
+
+	return result
+}
+
+// tagsFor returns the tags of the source AMI, transformed by the tag rules, for a copy in a region and account
+func (ami *Ami) tagsFor(region string, account string) ([]ec2Types.Tag, error) {
+	return ami.applyTagRules(ami.TagRules, region, account, ami.CopiedAt)
+}
+
+// tagsForSource returns the tags of the source AMI, transformed by the tag rules without the provenance tags, for
+// the source AMI in an account
+func (ami *Ami) tagsForSource(account string) ([]ec2Types.Tag, error) {
+	return ami.applyTagRules(ami.TagRules.withoutProvenance(), ami.SourceRegion, account, ami.CopiedAt)
+}
+
+// expectedTags returns the tags copy sets on an image in a region and account, without the tags that are managed by AWS
+// or by this tool. The source AMI keeps its own tags in the account that owns it. The tags of an existing copy are
+// rendered with the time it was copied.
+func (ami *Ami) expectedTags(image *ec2Types.Image, region string, account string) (map[string]string, error) {
+	if aws.ToString(image.ImageId) != ami.SourceAmiID {
+		tags, err := ami.applyTagRules(ami.TagRules, region, account, copiedAtOf(image))
+
+		if err != nil {
+			return nil, err
+		}
+
+		return comparableTags(tags), nil
+	}
+
+	if account == ami.ownerAccount() {
+		return comparableTags(image.Tags), nil
+	}
+
+	tags, err := ami.tagsForSource(account)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return comparableTags(tags), nil
+}
+
+func (ami *Ami) applyTagRules(rules *TagRules, region string, account string, copiedAt time.Time) ([]ec2Types.Tag, error) {
+	var sourceTags []ec2Types.Tag
+	if ami.SourceAmiTags != nil {
+		sourceTags = *ami.SourceAmiTags
+	}
+
+	return rules.Apply(sourceTags, TagTemplateData{
+		SourceAmiID:   ami.SourceAmiID,
+		SourceAmiName: ami.SourceAmiName,
+		SourceRegion:  ami.SourceRegion,
+		SourceAccount: ami.ownerAccount(),
+		Region:        region,
+		Account:       account,
+		CopiedAt:      copiedAt.Format(time.RFC3339),
+	})
+}
+
+func convertTagSliceToValueMap(tags []ec2Types.Tag) map[string]string {
+	tagMap := make(map[string]string, len(tags))
+
+	for _, tag := range tags {
+		tagMap[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+
+	return tagMap
+}
@@ -0,0 +1,269 @@
+package aws
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+func TestNewTagRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		add     []string
+		drop    []string
+		rename  []string
+		wantErr bool
+	}{
+		{name: "no rules"},
+		{name: "valid rules", add: []string{"copy-of={{.SourceAmiName}}", "empty="}, drop: []string{"Secret"}, rename: []string{"Version=version"}},
+		{name: "add without value", add: []string{"copy-of"}, wantErr: true},
+		{name: "add without key", add: []string{"=value"}, wantErr: true},
+		{name: "add with invalid template", add: []string{"copy-of={{.SourceAmiName"}, wantErr: true},
+		{name: "rename without new key", rename: []string{"Version="}, wantErr: true},
+		{name: "rename without old key", rename: []string{"=version"}, wantErr: true},
+		{name: "rename without equals sign", rename: []string{"Version"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewTagRules(tt.add, tt.drop, tt.rename, false)
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewTagRules() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTagRulesApply(t *testing.T) {
+	sourceTags := []ec2Types.Tag{
+		{Key: aws.String("Name"), Value: aws.String("web")},
+		{Key: aws.String("Version"), Value: aws.String("1.2")},
+		{Key: aws.String("Secret"), Value: aws.String("s3cr3t")},
+		{Key: aws.String("aws:cloudformation:stack-name"), Value: aws.String("stack")},
+	}
+
+	data := TagTemplateData{
+		SourceAmiID:   "ami-0123456789abcdef0",
+		SourceAmiName: "web-1.2",
+		SourceRegion:  "eu-west-1",
+		SourceAccount: "123456789012",
+		Region:        "eu-central-1",
+		Account:       "210987654321",
+		CopiedAt:      "2019-04-01T12:00:00Z",
+	}
+
+	tests := []struct {
+		name       string
+		add        []string
+		drop       []string
+		rename     []string
+		provenance bool
+		source     bool
+		want       map[string]string
+		wantErr    bool
+	}{
+		{
+			name: "no rules",
+			want: map[string]string{"Name": "web", "Version": "1.2", "Secret": "s3cr3t"},
+		},
+		{
+			name:   "drop and rename",
+			drop:   []string{"Secret"},
+			rename: []string{"Version=version"},
+			want:   map[string]string{"Name": "web", "version": "1.2"},
+		},
+		{
+			name: "add templates",
+			add:  []string{"copy-of={{.SourceAmiName}}", "target={{.Account}}/{{.Region}}", "name={{index .Tags \"Name\"}}"},
+			drop: []string{"Secret", "Version"},
+			want: map[string]string{"Name": "web", "copy-of": "web-1.2", "target": "210987654321/eu-central-1", "name": "web"},
+		},
+		{
+			name: "added tags override source tags",
+			add:  []string{"Name=copy"},
+			drop: []string{"Secret", "Version"},
+			want: map[string]string{"Name": "copy"},
+		},
+		{
+			name:       "provenance on a copy",
+			drop:       []string{"Secret", "Version"},
+			provenance: true,
+			want: map[string]string{
+				"Name":                                  "web",
+				"ami-manager:provenance:copied-from":    "ami-0123456789abcdef0",
+				"ami-manager:provenance:source-region":  "eu-west-1",
+				"ami-manager:provenance:source-account": "123456789012",
+				"ami-manager:provenance:copied-at":      "2019-04-01T12:00:00Z",
+			},
+		},
+		{
+			name:       "provenance overridden by an added tag",
+			add:        []string{"ami-manager:provenance:copied-at=never"},
+			drop:       []string{"Name", "Secret", "Version"},
+			provenance: true,
+			want: map[string]string{
+				"ami-manager:provenance:copied-from":    "ami-0123456789abcdef0",
+				"ami-manager:provenance:source-region":  "eu-west-1",
+				"ami-manager:provenance:source-account": "123456789012",
+				"ami-manager:provenance:copied-at":      "never",
+			},
+		},
+		{
+			name:       "no provenance on the source",
+			add:        []string{"copy-of={{.SourceAmiName}}"},
+			drop:       []string{"Secret", "Version"},
+			provenance: true,
+			source:     true,
+			want:       map[string]string{"Name": "web", "copy-of": "web-1.2"},
+		},
+		{
+			name:    "rename onto an existing tag",
+			rename:  []string{"Version=Name"},
+			wantErr: true,
+		},
+		{
+			name:    "two renames onto the same key",
+			rename:  []string{"Version=v", "Secret=v"},
+			wantErr: true,
+		},
+		{
+			name:   "rename onto a dropped tag",
+			drop:   []string{"Name"},
+			rename: []string{"Version=Name"},
+			want:   map[string]string{"Name": "1.2", "Secret": "s3cr3t"},
+		},
+		{
+			name:    "missing key in template",
+			add:     []string{"env={{.Environment}}"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := NewTagRules(tt.add, tt.drop, tt.rename, tt.provenance)
+
+			if err != nil {
+				t.Fatalf("NewTagRules() error = %v", err)
+			}
+
+			if tt.source {
+				rules = rules.withoutProvenance()
+			}
+
+			got, err := rules.Apply(sourceTags, data)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Apply() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if gotMap := convertTagSliceToValueMap(got); !reflect.DeepEqual(gotMap, tt.want) {
+				t.Errorf("Apply() = %v, want %v", gotMap, tt.want)
+			}
+		})
+	}
+}
+
+func TestTagRulesApplyWithoutRules(t *testing.T) {
+	var rules *TagRules
+
+	got, err := rules.Apply([]ec2Types.Tag{
+		{Key: aws.String("Name"), Value: aws.String("web")},
+		{Key: aws.String("aws:createdBy"), Value: aws.String("packer")},
+	}, TagTemplateData{})
+
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+
+	want := map[string]string{"Name": "web"}
+	if gotMap := convertTagSliceToValueMap(got); !reflect.DeepEqual(gotMap, want) {
+		t.Errorf("Apply() = %v, want %v", gotMap, want)
+	}
+}
+
+func TestExpectedTags(t *testing.T) {
+	sourceTags := []ec2Types.Tag{
+		{Key: aws.String("Name"), Value: aws.String("web")},
+		{Key: aws.String("Version"), Value: aws.String("1.2")},
+	}
+
+	rules, err := NewTagRules([]string{"copied={{.CopiedAt}}", "target={{.Account}}"}, nil, []string{"Version=version"}, true)
+
+	if err != nil {
+		t.Fatalf("NewTagRules() error = %v", err)
+	}
+
+	ami := &Ami{
+		SourceAmiID:   "ami-0123456789abcdef0",
+		SourceAmiName: "web-1.2",
+		SourceRegion:  "eu-west-1",
+		SourceAmiTags: &sourceTags,
+		AWSImage: &ec2Types.Image{
+			ImageId: aws.String("ami-0123456789abcdef0"),
+			OwnerId: aws.String("123456789012"),
+			Tags:    append([]ec2Types.Tag{{Key: aws.String("owner-only"), Value: aws.String("true")}}, sourceTags...),
+		},
+		TagRules: rules,
+	}
+
+	tests := []struct {
+		name    string
+		image   *ec2Types.Image
+		account string
+		want    map[string]string
+	}{
+		{
+			name:    "source in its owner",
+			image:   ami.AWSImage,
+			account: "123456789012",
+			want:    map[string]string{"owner-only": "true", "Name": "web", "Version": "1.2"},
+		},
+		{
+			name:    "source in another account",
+			image:   ami.AWSImage,
+			account: "210987654321",
+			want:    map[string]string{"Name": "web", "version": "1.2", "copied": "0001-01-01T00:00:00Z", "target": "210987654321"},
+		},
+		{
+			name: "copy with the copied-at tag",
+			image: &ec2Types.Image{
+				ImageId:      aws.String("ami-0fedcba9876543210"),
+				CreationDate: aws.String("2019-04-01T12:03:00.000Z"),
+				Tags:         []ec2Types.Tag{{Key: aws.String(CopiedAtTag), Value: aws.String("2019-04-01T12:00:00Z")}},
+			},
+			account: "210987654321",
+			want:    map[string]string{"Name": "web", "version": "1.2", "copied": "2019-04-01T12:00:00Z", "target": "210987654321"},
+		},
+		{
+			name: "copy without the copied-at tag",
+			image: &ec2Types.Image{
+				ImageId:      aws.String("ami-0fedcba9876543210"),
+				CreationDate: aws.String("2019-04-01T12:03:00.000Z"),
+			},
+			account: "123456789012",
+			want:    map[string]string{"Name": "web", "version": "1.2", "copied": "2019-04-01T12:03:00Z", "target": "123456789012"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ami.expectedTags(tt.image, "eu-central-1", tt.account)
+
+			if err != nil {
+				t.Fatalf("expectedTags() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expectedTags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// Unshare takes the AMI and its copies in the regions away from the principals, which are account ID's, organization
+// ARN's or organizational unit ARN's. The launch permissions are removed, together with the create volume permissions
+// of the snapshots for accounts. With removeTags, the tags of the source AMI are first removed in the accounts.
+func (ami *Ami) Unshare(regions []string, principals []string, removeTags bool) error {
+	err := ami.fetchMetadata()
+
+	if err != nil {
+		return err
+	}
+
+	launchPermissions, err := createLaunchPermissions(principals)
+
+	if err != nil {
+		return err
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+
+	for _, region := range regions {
+		wg.Add(1)
+		go func(region string) {
+			defer wg.Done()
+
+			err := ami.unshareRegion(region, principals, launchPermissions, removeTags)
+
+			if err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("region %s: %w", region, err))
+				mu.Unlock()
+			}
+		}(region)
+	}
+
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+func (ami *Ami) unshareRegion(region string, principals []string, launchPermissions []ec2Types.LaunchPermission, removeTags bool) error {
+	var images []ec2Types.Image
+
+	if region == ami.SourceRegion {
+		images = []ec2Types.Image{*ami.AWSImage}
+	} else {
+		copies, err := ami.findCopies(region)
+
+		if err != nil {
+			return err
+		}
+
+		images = copies
+	}
+
+	ec2Service := getEC2ServiceForAccountAndRegion(*ConfigManager.defaultAccountID, region)
+
+	for i := range images {
+		image := &images[i]
+		log.Infof("Unsharing AMI %s in region %s", *image.ImageId, region)
+
+		// the image is no longer visible to the account once the launch permission is removed
+		if removeTags && ami.SourceAmiTags != nil && len(*ami.SourceAmiTags) > 0 {
+			var accountIDs []string
+			for _, principal := range principals {
+				if isAccountID(principal) && principal != *ConfigManager.defaultAccountID {
+					accountIDs = append(accountIDs, principal)
+				}
+			}
+
+			tagAccounts, err := ConfigManager.withCredentials(accountIDs)
+
+			if err != nil {
+				return err
+			}
+
+			for _, principal := range tagAccounts {
+
+				err := removeTagsForAccount(principal, region, *image.ImageId, *ami.SourceAmiTags)
+
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		modifyImageAttributeInput := &ec2.ModifyImageAttributeInput{
+			ImageId: image.ImageId,
+			LaunchPermission: &ec2Types.LaunchPermissionModifications{
+				Remove: launchPermissions,
+			},
+		}
+
+		_, err := ec2Service.ModifyImageAttribute(context.Background(), modifyImageAttributeInput)
+
+		if err != nil {
+			return err
+		}
+
+		err = removeCreateVolumePermissions(image, principals, ec2Service)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// removeCreateVolumePermissions removes the create volume permissions of the accounts from the snapshots of the image.
+// Snapshots can't be shared with organizations, so only account ID's are taken into account.
+func removeCreateVolumePermissions(image *ec2Types.Image, principals []string, ec2Service *ec2.Client) error {
+	var permissions []ec2Types.CreateVolumePermission
+	for _, principal := range principals {
+		if isAccountID(principal) {
+			permissions = append(permissions, ec2Types.CreateVolumePermission{UserId: aws.String(principal)})
+		}
+	}
+
+	if len(permissions) == 0 {
+		return nil
+	}
+
+	for _, mapping := range image.BlockDeviceMappings {
+		if mapping.Ebs == nil || mapping.Ebs.SnapshotId == nil {
+			continue
+		}
+
+		log.Debugf("Removing create volume permissions from snapshot %s", *mapping.Ebs.SnapshotId)
+
+		modifySnapshotAttributeInput := &ec2.ModifySnapshotAttributeInput{
+			SnapshotId: mapping.Ebs.SnapshotId,
+			Attribute:  ec2Types.SnapshotAttributeNameCreateVolumePermission,
+			CreateVolumePermission: &ec2Types.CreateVolumePermissionModifications{
+				Remove: permissions,
+			},
+		}
+
+		_, err := ec2Service.ModifySnapshotAttribute(context.Background(), modifySnapshotAttributeInput)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// createLaunchPermissions converts account ID's, organization ARN's and organizational unit ARN's to launch permissions
+func createLaunchPermissions(principals []string) ([]ec2Types.LaunchPermission, error) {
+	launchPermissions := make([]ec2Types.LaunchPermission, 0, len(principals))
+
+	for _, principal := range principals {
+		switch {
+		case isAccountID(principal):
+			launchPermissions = append(launchPermissions, ec2Types.LaunchPermission{UserId: aws.String(principal)})
+		case strings.HasPrefix(principal, "arn:") && strings.Contains(principal, ":ou/"):
+			launchPermissions = append(launchPermissions, ec2Types.LaunchPermission{OrganizationalUnitArn: aws.String(principal)})
+		case strings.HasPrefix(principal, "arn:") && strings.Contains(principal, ":organization/"):
+			launchPermissions = append(launchPermissions, ec2Types.LaunchPermission{OrganizationArn: aws.String(principal)})
+		default:
+			return nil, fmt.Errorf("%q is not an account ID, organization ARN or organizational unit ARN", principal)
+		}
+	}
+
+	return launchPermissions, nil
+}
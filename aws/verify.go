@@ -0,0 +1,291 @@
+package aws
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	missing string = "(missing)"
+)
+
+// Drift is a difference between the source AMI and one of its copies, as seen from an account
+type Drift struct {
+	Region   string `json:"region"`
+	Account  string `json:"account"`
+	AmiID    string `json:"amiId"`
+	Field    string `json:"field"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+// Verify compares the name, tags, launch permissions, encryption and boot mode of the AMI with its copies in the
+// regions, and the tags of every copy in every account of the ConfigManager.
+func (ami *Ami) Verify(regions []string) ([]Drift, error) {
+	err := ami.fetchMetadata()
+
+	if err != nil {
+		return nil, err
+	}
+
+	// the launch permissions of a shared source AMI are managed by the account that owns it
+	var sourceSharedWith []string
+
+	if !ami.isShared() {
+		sourceSharedWith, err = getSharedWith(getEC2ServiceForAccountAndRegion(*ConfigManager.defaultAccountID, ami.SourceRegion), ami.SourceAmiID)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// the copies should at least be shared with the accounts given to the command, the source AMI itself is not
+	// expected to be shared with them
+	copySharedWith := toSet(sourceSharedWith)
+	for _, account := range ConfigManager.getAccounts() {
+		if account != *ConfigManager.defaultAccountID {
+			copySharedWith[account] = true
+		}
+	}
+
+	var drifts []Drift
+
+	for _, region := range regions {
+		var images []ec2Types.Image
+
+		expectedSharedWith := copySharedWith
+
+		// a shared source AMI is copied into the source region as well
+		if region == ami.SourceRegion && !ami.isShared() {
+			images = []ec2Types.Image{*ami.AWSImage}
+			expectedSharedWith = toSet(sourceSharedWith)
+		} else {
+			images, err = ami.findCopies(region)
+
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if len(images) == 0 {
+			drifts = append(drifts, Drift{
+				Region:   region,
+				Account:  *ConfigManager.defaultAccountID,
+				Field:    "copy",
+				Expected: ami.SourceAmiID,
+				Actual:   missing,
+			})
+			continue
+		}
+
+		for i := range images {
+			imageDrifts, err := ami.verifyImage(region, &images[i], expectedSharedWith)
+
+			if err != nil {
+				return nil, err
+			}
+
+			drifts = append(drifts, imageDrifts...)
+		}
+	}
+
+	log.Debugf("Found %d differences", len(drifts))
+
+	return drifts, nil
+}
+
+func (ami *Ami) verifyImage(region string, image *ec2Types.Image, expectedSharedWith map[string]bool) ([]Drift, error) {
+	log.Debugf("Verifying AMI %s in region %s", *image.ImageId, region)
+	owner := *ConfigManager.defaultAccountID
+
+	newDrift := func(account string, field string, expected string, actual string) Drift {
+		return Drift{
+			Region:   region,
+			Account:  account,
+			AmiID:    *image.ImageId,
+			Field:    field,
+			Expected: expected,
+			Actual:   actual,
+		}
+	}
+
+	var drifts []Drift
+
+	expectedName, err := ami.expectedName(image, region)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if aws.ToString(image.Name) != expectedName {
+		drifts = append(drifts, newDrift(owner, "name", expectedName, aws.ToString(image.Name)))
+	}
+
+	if isEncrypted(image) != isEncrypted(ami.AWSImage) {
+		drifts = append(drifts, newDrift(owner, "encrypted", strconv.FormatBool(isEncrypted(ami.AWSImage)), strconv.FormatBool(isEncrypted(image))))
+	}
+
+	if image.BootMode != ami.AWSImage.BootMode {
+		drifts = append(drifts, newDrift(owner, "boot-mode", string(ami.AWSImage.BootMode), string(image.BootMode)))
+	}
+
+	sharedWith, err := getSharedWith(getEC2ServiceForAccountAndRegion(owner, region), *image.ImageId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	actualSharedWith := toSet(sharedWith)
+	for _, account := range sortedKeys(expectedSharedWith) {
+		if !actualSharedWith[account] {
+			drifts = append(drifts, newDrift(owner, "launch-permission", account, missing))
+		}
+	}
+	for _, account := range sortedKeys(actualSharedWith) {
+		if !expectedSharedWith[account] {
+			drifts = append(drifts, newDrift(owner, "launch-permission", missing, account))
+		}
+	}
+
+	expectedTags, err := ami.expectedTags(image, region, owner)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tagDrift := range compareTags(expectedTags, comparableTags(image.Tags)) {
+		drifts = append(drifts, newDrift(owner, tagDrift[0], tagDrift[1], tagDrift[2]))
+	}
+
+	accounts, err := ConfigManager.withCredentials(ConfigManager.getAccounts())
+
+	if err != nil {
+		return nil, err
+	}
+
+	// shared AMI's have their own tags in every account
+	for _, account := range accounts {
+		if account == owner {
+			continue
+		}
+
+		tags, err := describeTagsForAccount(account, region, *image.ImageId)
+
+		if err != nil {
+			drifts = append(drifts, newDrift(account, "visible", "true", err.Error()))
+			continue
+		}
+
+		accountTags, err := ami.expectedTags(image, region, account)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, tagDrift := range compareTags(accountTags, comparableTags(tags)) {
+			drifts = append(drifts, newDrift(account, tagDrift[0], tagDrift[1], tagDrift[2]))
+		}
+	}
+
+	return drifts, nil
+}
+
+// describeTagsForAccount returns the tags of an image as seen by the account
+func describeTagsForAccount(account string, region string, imageID string) ([]ec2Types.Tag, error) {
+	ec2svc := getEC2ServiceForAccountAndRegion(account, region)
+
+	result, err := ec2svc.DescribeImages(context.Background(), &ec2.DescribeImagesInput{
+		ImageIds: []string{imageID},
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result.Images) == 0 {
+		return nil, nil
+	}
+
+	return result.Images[0].Tags, nil
+}
+
+// compareTags returns a field, expected and actual value for every tag that differs
+func compareTags(expected map[string]string, actual map[string]string) [][3]string {
+	var differences [][3]string
+
+	for _, key := range sortedKeys(expected) {
+		value, ok := actual[key]
+
+		if !ok {
+			value = missing
+		}
+
+		if value != expected[key] {
+			differences = append(differences, [3]string{"tag:" + key, expected[key], value})
+		}
+	}
+
+	for _, key := range sortedKeys(actual) {
+		if _, ok := expected[key]; !ok {
+			differences = append(differences, [3]string{"tag:" + key, missing, actual[key]})
+		}
+	}
+
+	return differences
+}
+
+// comparableTags converts the tags to a map, without the tags that are managed by AWS or by this tool
+func comparableTags(tags []ec2Types.Tag) map[string]string {
+	tagMap := make(map[string]string)
+
+	for _, tag := range tags {
+		key := aws.ToString(tag.Key)
+
+		if strings.HasPrefix(key, "aws:") || strings.HasPrefix(key, "ami-manager:") {
+			continue
+		}
+
+		tagMap[key] = aws.ToString(tag.Value)
+	}
+
+	return tagMap
+}
+
+func isEncrypted(image *ec2Types.Image) bool {
+	for _, mapping := range image.BlockDeviceMappings {
+		if mapping.Ebs != nil && aws.ToBool(mapping.Ebs.Encrypted) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func toSet(slice []string) map[string]bool {
+	set := make(map[string]bool, len(slice))
+
+	for _, s := range slice {
+		set[s] = true
+	}
+
+	return set
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,66 @@
+package aws
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompareTags(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected map[string]string
+		actual   map[string]string
+		want     [][3]string
+	}{
+		{
+			name:     "equal",
+			expected: map[string]string{"Name": "web", "Env": "prod"},
+			actual:   map[string]string{"Env": "prod", "Name": "web"},
+			want:     nil,
+		},
+		{
+			name:     "both empty",
+			expected: map[string]string{},
+			actual:   map[string]string{},
+			want:     nil,
+		},
+		{
+			name:     "different value",
+			expected: map[string]string{"Env": "prod"},
+			actual:   map[string]string{"Env": "dev"},
+			want:     [][3]string{{"tag:Env", "prod", "dev"}},
+		},
+		{
+			name:     "missing tag",
+			expected: map[string]string{"Env": "prod", "Name": "web"},
+			actual:   map[string]string{"Name": "web"},
+			want:     [][3]string{{"tag:Env", "prod", missing}},
+		},
+		{
+			name:     "extra tag",
+			expected: map[string]string{"Name": "web"},
+			actual:   map[string]string{"Name": "web", "Owner": "ops"},
+			want:     [][3]string{{"tag:Owner", missing, "ops"}},
+		},
+		{
+			name:     "sorted by key, differences before extra tags",
+			expected: map[string]string{"b": "1", "a": "1"},
+			actual:   map[string]string{"c": "1", "b": "2"},
+			want: [][3]string{
+				{"tag:a", "1", missing},
+				{"tag:b", "1", "2"},
+				{"tag:c", missing, "1"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := compareTags(tt.expected, tt.actual)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("compareTags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -15,6 +15,9 @@
 package cmd
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/cloudnatives/aws-ami-manager/aws"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -31,7 +34,18 @@ var cleanupCmd = &cobra.Command{
 	Short: "Cleanup earlier versions of the AMI",
 	Long: `Cleanup earlier versions in the different regions. 
 
-It keeps the most recent version with the same tags and AMI's that are currently in use.		
+It keeps the most recent version with the same tags and AMI's that are currently in use.
+
+The AMI's are listed and have to be confirmed before they are removed, unless --yes is given. AMI's with the protection
+tag or with deregistration protection are skipped, unless --force is given.
+
+The tags that the accounts given with --accounts or --accounts-from-org have set on the AMI's are removed as well.
+
+When the AMI is shared with this account, --source-account gives the account that owns it. Its tags are read in that
+account, and only the copies owned by this account are cleaned up.
+
+Before anything is removed, the permissions are checked in every account and region with DryRun requests, see the doctor
+command. Use --skip-preflight to skip these checks.
 	`,
 	Run: func(cmd *cobra.Command, args []string) {
 		runCleanup()
@@ -39,14 +53,44 @@ It keeps the most recent version with the same tags and AMI's that are currently
 }
 
 func runCleanup() {
-	cm := aws.NewConfigurationManager()
+	loadAWSConfigForProfiles()
+	addSourceAccount()
+	loadProtectionPolicy()
+
+	region, err := resolveSourceRegion(amiID)
+
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	ami := aws.NewAmi(amiID)
-	ami.SourceRegion = cm.GetDefaultRegion()
+	ami.SourceRegion = region
+	ami.SourceAccount = sourceAccount
+
+	plan, err := ami.PlanCleanup(regions, tagsToMatch, versionsToKeep)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if plan.IsEmpty() {
+		log.Infof("There are no older AMI's related to %s to clean up", ami.SourceAmiID)
+		return
+	}
+
+	runPreflight(aws.PreflightCleanup, ami.SourceAmiID, region, regions)
 
-	aws.ConfigManager = cm
+	question := "Remove these AMI's?"
+	if len(accounts) > 0 {
+		question = fmt.Sprintf("Remove these AMI's and their tags in %s?", strings.Join(accounts, ", "))
+	}
+
+	if !confirm(question, plan.Describe()) {
+		log.Info("Nothing has been removed")
+		return
+	}
 
-	err := ami.Cleanup(regions, tagsToMatch, versionsToKeep)
+	err = plan.Execute()
 
 	if err != nil {
 		log.Fatal(err)
@@ -61,6 +105,14 @@ func init() {
 	cleanupCmd.Flags().StringVar(&amiID, "amiID", "", "The source AMI ID, e.g. aws-0e38957fc6310ea8b")
 	_ = cleanupCmd.MarkFlagRequired("amiID")
 
+	cleanupCmd.Flags().StringVar(&sourceAccount, "source-account", "", "The account that owns the source AMI, when it is shared with this account. Its tags are read in that account")
+	cleanupCmd.Flags().StringVar(&sourceRole, "source-role", "", "The AWS IAM role to assume in the source account. Defaults to --role")
+	cleanupCmd.Flags().StringSliceVar(&accounts, "accounts", []string{}, "The account ID's the AMI's have been shared with. Can be multiple flags, or a comma-separated value")
+	addOrgFlags(cleanupCmd)
+	cleanupCmd.Flags().StringVar(&role, "role", "terraform", "The AWS IAM role to assume in the organizations, e.g. OrganizationAccountAssumeRole. Defaults to `terraform`.")
+
+	cleanupCmd.Flags().StringVar(&sourceRegion, "source-region", "", "The region of the source AMI. When not given, the AMI is looked up in the current region and then in the other enabled regions")
+
 	cleanupCmd.Flags().StringSliceVar(&regions, "regions", []string{}, "The regions to copy this AMI to. Can be multiple flags, or a comma-separated value")
 	_ = cleanupCmd.MarkFlagRequired("regions")
 
@@ -68,4 +120,7 @@ func init() {
 	_ = cleanupCmd.MarkFlagRequired("regions")
 
 	cleanupCmd.Flags().IntVar(&versionsToKeep, "versions-to-keep", 5, "The number of AMI's you would like to keep. Defaults to 5.")
+
+	addProtectionFlags(cleanupCmd)
+	addPreflightFlag(cleanupCmd)
 }
@@ -15,6 +15,8 @@
 package cmd
 
 import (
+	"fmt"
+	"os"
 	"time"
 
 	"github.com/cloudnatives/aws-ami-manager/aws"
@@ -23,7 +25,27 @@ import (
 )
 
 var (
-	accounts []string
+	accounts       []string
+	addTags        []string
+	dropTags       []string
+	renameTags     []string
+	provenanceTags bool
+
+	nameTemplate        string
+	descriptionTemplate string
+
+	ssmParameter   string
+	ssmLabel       string
+	ssmAllAccounts bool
+
+	packerManifest  string
+	imageBuilderArn string
+
+	sourceAccount string
+	sourceRole    string
+
+	deepCopy     bool
+	targetKmsKey string
 )
 
 // copyCmd represents the copy command
@@ -33,6 +55,34 @@ var copyCmd = &cobra.Command{
 	Long: `Copies an AMI to a list of AWS regions and accounts.
 
 E.g. aws-ami-manager copy --amiID=ami-0e38977fc6310ea8b --regions=eu-west-1,eu-central-1 --accounts=123456789,987654321,192837465
+
+Instead of --amiID, the AMI can be taken from the last build in a Packer manifest with --from-packer-manifest, or from
+an EC2 Image Builder image with --from-image-builder. The region of the AMI is taken from the manifest or the image.
+
+The tags of the source AMI are set on the copies and in the accounts. They can be dropped with --drop-tag, renamed with
+--rename-tag and added with --add-tag. The values of added tags are Go templates with the fields SourceAmiID,
+SourceAmiName, SourceRegion, SourceAccount, Region, Account, CopiedAt and Tags, e.g. --add-tag='copy-of={{.SourceAmiName}}'.
+
+The names and descriptions of the copies can be set with --name-template and --description-template. These Go templates
+have the fields SourceAmiID, SourceAmiName, SourceRegion, Region, Date and GitSHA, which is read from the GIT_SHA,
+GIT_COMMIT, GITHUB_SHA or CI_COMMIT_SHA environment variable. The names are validated before anything is copied.
+
+An AMI that is shared by another account is copied to its own region as well, so this account owns all copies. The tags
+of a shared AMI are not visible to this account. With --source-account, the role given with --source-role is assumed in
+the account that owns the AMI to read its name and tags.
+
+E.g. aws-ami-manager copy --amiID=ami-0e38977fc6310ea8b --source-account=111122223333 --source-role=ami-reader --regions=eu-west-1 --accounts=123456789
+
+With --deep-copy, the role is assumed in every account to copy the shared AMI into the account itself, so every account
+owns an independent copy that keeps working when the AMI in this account is removed. The copies are encrypted with the
+KMS key given with --target-kms-key, which must exist in every account, or with the default EBS key of the account.
+The accounts are granted the use of the customer managed KMS key of an encrypted AMI while copying.
+
+Before anything is copied, the permissions are checked in every account and region with DryRun requests, see the doctor
+command. Use --skip-preflight to skip these checks.
+
+With --ssm-parameter, the AMI ID's are published to an SSM parameter in every region once all copies are available.
+The parameter name is a Go template with the fields Name, SourceAmiID, Region and Account.
 	`,
 	Run: func(cmd *cobra.Command, args []string) {
 		runCopy()
@@ -40,12 +90,60 @@ E.g. aws-ami-manager copy --amiID=ami-0e38977fc6310ea8b --regions=eu-west-1,eu-c
 }
 
 func runCopy() {
-	log.Infof("Started copying AMI %s", amiID)
+	log.Info("Started copying AMI")
 	start := time.Now()
 
+	err := requireAccounts()
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	tagRules, err := aws.NewTagRules(addTags, dropTags, renameTags, provenanceTags)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	naming, err := aws.NewNaming(nameTemplate, descriptionTemplate)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var ssmPublisher *aws.SSMPublisher
+	if ssmParameter != "" {
+		ssmPublisher, err = aws.NewSSMPublisher(ssmParameter, ssmLabel, ssmAllAccounts)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if targetKmsKey != "" && !deepCopy {
+		log.Fatal("--target-kms-key can only be used with --deep-copy")
+	}
+
 	loadAWSConfigForProfiles()
 
-	ami := aws.NewAmiWithRegions(amiID, aws.ConfigManager.GetDefaultRegion(), regions)
+	addSourceAccount()
+
+	source, err := resolveCopySource()
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	runPreflight(aws.PreflightCopy, source.AmiID, source.Region, regions)
+
+	ami := aws.NewAmiWithRegions(source.AmiID, source.Region, regions)
+	ami.SourceAccount = sourceAccount
+	ami.TagRules = tagRules
+	ami.Naming = naming
+	ami.SSMPublisher = ssmPublisher
+	if deepCopy {
+		ami.DeepCopy = aws.NewDeepCopy(targetKmsKey)
+	}
 	ami.Copy()
 
 	elapsed := time.Since(start)
@@ -56,17 +154,169 @@ func init() {
 	rootCmd.AddCommand(copyCmd)
 
 	copyCmd.Flags().StringVar(&amiID, "amiID", "", "The source AMI ID, e.g. aws-0e38957fc6310ea8b")
-	_ = copyCmd.MarkFlagRequired("amiID")
+	copyCmd.Flags().StringVar(&sourceRegion, "source-region", "", "The region of the source AMI. When not given, the AMI is looked up in the current region and then in the other enabled regions")
+	copyCmd.Flags().StringVar(&sourceAccount, "source-account", "", "The account that owns the source AMI, when it is shared with this account. Its name and tags are read in that account")
+	copyCmd.Flags().StringVar(&sourceRole, "source-role", "", "The AWS IAM role to assume in the source account. Defaults to --role")
+	copyCmd.Flags().StringVar(&packerManifest, "from-packer-manifest", "", "Copy the AMI of the last build in this Packer manifest, instead of --amiID")
+	copyCmd.Flags().StringVar(&imageBuilderArn, "from-image-builder", "", "Copy the AMI of this EC2 Image Builder image build version ARN, instead of --amiID")
 
-	copyCmd.Flags().StringSliceVar(&regions, "regions", []string{}, "The regions to copy this AMI to. Can be multiple flags, or a comma-separated value")
+	copyCmd.Flags().StringSliceVar(&regions, "regions", []string{}, "The regions to copy this AMI to, all for every enabled region or opted-in for the opt-in regions. Prefix a region with - to exclude it, e.g. all,-us-east-1. Can be multiple flags, or a comma-separated value")
 	_ = copyCmd.MarkFlagRequired("regions")
 
 	copyCmd.Flags().StringSliceVar(&accounts, "accounts", []string{}, "The account ID's that will be authorized to use the Ami's. Can be multiple flags, or a comma-separated value")
-	_ = copyCmd.MarkFlagRequired("accounts")
+	addOrgFlags(copyCmd)
 
 	copyCmd.Flags().StringVar(&role, "role", "terraform", "The AWS IAM role to assume in the organizations, e.g. OrganizationAccountAssumeRole. Defaults to `terraform`.")
+
+	addTagRuleFlags(copyCmd)
+	copyCmd.Flags().StringVar(&nameTemplate, "name-template", "", "A Go template for the names of the copies, e.g. '{{.SourceAmiName}}-{{.Region}}'. Defaults to the name of the source AMI")
+	copyCmd.Flags().StringVar(&descriptionTemplate, "description-template", "", "A Go template for the descriptions of the copies, e.g. 'Copy of {{.SourceAmiID}} built from {{.GitSHA}}'")
+	copyCmd.Flags().StringVar(&ssmParameter, "ssm-parameter", "", "Publish the AMI ID's to this SSM parameter in every region. A Go template, e.g. '/ami/{{.Name}}/latest'")
+	copyCmd.Flags().StringVar(&ssmLabel, "ssm-label", "", "The label to put on the new version of the SSM parameter, e.g. production")
+	copyCmd.Flags().BoolVar(&ssmAllAccounts, "ssm-all-accounts", false, "Publish the SSM parameter in every account, instead of only in this account")
+	copyCmd.Flags().BoolVar(&deepCopy, "deep-copy", false, "Copy the AMI into every account as well, so the accounts own independent copies")
+	copyCmd.Flags().StringVar(&targetKmsKey, "target-kms-key", "", "The KMS key in the accounts to encrypt the deep copies with, e.g. alias/ami. Defaults to the default EBS key of the account")
+	addPreflightFlag(copyCmd)
+	copyCmd.Flags().BoolVar(&provenanceTags, "provenance-tags", false, "Add the ami-manager:provenance:copied-from, source-region, source-account and copied-at tags to the copies")
+}
+
+// addTagRuleFlags adds the flags of the tag rules. Verify and sync take the same flags as copy, to expect the same tags.
+func addTagRuleFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVar(&addTags, "add-tag", []string{}, "A tag to add to the copies, formatted as key=value. The value is a Go template, e.g. built-from={{.SourceAmiName}}. Can be multiple flags")
+	cmd.Flags().StringSliceVar(&dropTags, "drop-tag", []string{}, "A tag of the source AMI that is not copied. Can be multiple flags, or a comma-separated value")
+	cmd.Flags().StringSliceVar(&renameTags, "rename-tag", []string{}, "A tag of the source AMI that is copied with another key, formatted as old=new. Can be multiple flags, or a comma-separated value")
 }
 
+// resolveCopySource returns the AMI to copy, given as an AMI ID in the current region, by a Packer manifest or by an
+// Image Builder image
+func resolveCopySource() (*aws.AmiSource, error) {
+	given := 0
+	for _, flag := range []string{amiID, packerManifest, imageBuilderArn} {
+		if flag != "" {
+			given++
+		}
+	}
+
+	if given != 1 {
+		return nil, fmt.Errorf("exactly one of --amiID, --from-packer-manifest or --from-image-builder is required")
+	}
+
+	switch {
+	case packerManifest != "":
+		return aws.ResolvePackerManifest(packerManifest)
+	case imageBuilderArn != "":
+		return aws.ResolveImageBuilderImage(imageBuilderArn)
+	default:
+		region, err := resolveSourceRegion(amiID)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return &aws.AmiSource{
+			AmiID:  amiID,
+			Region: region,
+		}, nil
+	}
+}
+
+// resolveSourceRegion returns --source-region when it is given. Otherwise the AMI is looked up in the current region
+// first, and then in the other enabled regions.
+func resolveSourceRegion(amiID string) (string, error) {
+	if sourceRegion != "" {
+		return sourceRegion, nil
+	}
+
+	return aws.LocateAmi(amiID, aws.ConfigManager.GetDefaultRegion())
+}
+
+// addSourceAccount adds the account given with --source-account, so a shared source AMI can be read in that account
+func addSourceAccount() {
+	if sourceAccount == "" {
+		return
+	}
+
+	aws.ConfigManager.AddSourceAccount(sourceAccount, firstNonEmpty(sourceRole, role))
+}
+
+// loadAWSConfigForProfiles loads the configuration for a command that acts in the accounts. The roles are assumed when
+// an account is first used, and with --skip-failed-accounts, the accounts in which that fails are left out and reported
+// as a partial failure when the command has finished.
 func loadAWSConfigForProfiles() {
-	aws.ConfigManager = aws.NewConfigurationManagerForRegionsAndAccounts(regions, accounts, role)
+	loadAWSConfig()
+	aws.ConfigManager.SetSkipFailedAccounts(skipFailedAccounts)
+}
+
+// loadAWSConfig creates the configuration manager for the accounts, including the accounts found in the organization
+func loadAWSConfig() {
+	var accountsConfig *aws.AccountsConfig
+
+	if accountsConfigFile != "" {
+		var err error
+		accountsConfig, err = aws.LoadAccountsConfig(accountsConfigFile)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	var credentials *aws.CredentialsProvider
+
+	if credentialsFile := firstNonEmpty(stsCredentialsFile, os.Getenv(aws.StsCredentialsEnvVar)); credentialsFile != "" {
+		var err error
+		credentials, err = aws.LoadCredentialsProvider(credentialsFile)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	var endpoints *aws.EndpointOverrides
+
+	if len(endpointOverrides) > 0 {
+		var err error
+		endpoints, err = aws.ParseEndpointOverrides(endpointOverrides)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	configManager, err := aws.NewConfigurationManagerForRegionsAndAccounts(regions, accounts, role, accountsConfig, credentials, endpoints)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	aws.ConfigManager = configManager
+
+	validateRegions()
+
+	err = loadOrgAccounts()
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// validateRegions expands all and opted-in in the regions and removes the excluded regions. The regions are checked
+// against the enabled regions of the account, and the source region against the partition of the credentials, before
+// anything is changed.
+func validateRegions() {
+	var err error
+	regions, err = aws.ConfigManager.ResolveRegions(regions)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if sourceRegion == "" {
+		return
+	}
+
+	err = aws.ConfigManager.ValidateRegions([]string{sourceRegion})
+
+	if err != nil {
+		log.Fatal(err)
+	}
 }
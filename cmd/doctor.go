@@ -0,0 +1,258 @@
+// Copyright © 2019 Jeroen Schepens <jeroen@cloudnatives.be>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/cloudnatives/aws-ami-manager/aws"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	skipPreflight    bool
+	preflightCommand string
+)
+
+// the columns of the preflight matrix, in order
+var checkColumns = []string{
+	aws.CheckCredentials,
+	aws.CheckAssumeRole,
+	aws.CheckDescribeImages,
+	aws.CheckCopyImage,
+	aws.CheckModifyImageAttribute,
+	aws.CheckCreateTags,
+	aws.CheckDeleteTags,
+	aws.CheckDeregisterImage,
+	aws.CheckSourceKmsKey,
+	aws.CheckTargetKmsKey,
+}
+
+// doctorCmd represents the doctor command
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Checks the permissions in every account and region",
+	Long: `Checks that the role can be assumed in every account, and that the actions of a command are allowed in every
+account and region, without changing anything. The actions are checked with DryRun requests. The command is given with
+--command: copy (the default), cleanup, remove, sync, unshare or promote.
+
+With --amiID, the actions on the AMI are checked as well. For copy, these are copying, sharing and tagging it, together
+with the KMS key of its snapshots. For cleanup and remove, these are revoking the launch permissions, removing the tags
+in the accounts and deregistering it. For sync, these are sharing it and setting and removing its tags, for unshare
+revoking the launch permissions and removing the tags in the accounts, and for promote setting and removing its tags. The actions are checked against the AMI in its own region and against its copies
+in the other regions, so they are skipped in the regions without a copy yet. With --target-kms-key, the key for deep
+copies is checked in every account. A KMS key is only reported as usable when a data key can be generated and a grant
+can be created with it, as EBS does to copy encrypted snapshots.
+
+The results are printed as a matrix of the checks per account and region, followed by the errors and the skipped checks.
+
+E.g. ./aws-ami-manager doctor --amiID=ami-0e38977fc6310ea8b --regions=eu-west-1,eu-central-1 --accounts=123456789,987654321
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runDoctor()
+	},
+}
+
+func runDoctor() {
+	// the roles are assumed by the checks, so the accounts in which that fails are reported in the matrix
+	loadAWSConfig()
+
+	region := ""
+	if amiID != "" {
+		var err error
+		region, err = resolveSourceRegion(amiID)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	preflight, err := aws.NewPreflight(preflightCommand, amiID, region, targetKmsKey)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	checks := preflight.Run(regions)
+
+	err = printChecks(outputFormat, checks)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if failed := aws.FailedChecks(checks); len(failed) > 0 {
+		log.Fatalf("%d of %d checks failed", len(failed), len(checks))
+	}
+
+	if skipped := aws.SkippedChecks(checks); len(skipped) > 0 {
+		log.Infof("%d checks passed, %d checks were skipped", len(checks)-len(skipped), len(skipped))
+		return
+	}
+
+	log.Infof("All %d checks passed", len(checks))
+}
+
+// runPreflight checks the permissions of a mutating command before anything is changed, and stops when a check fails
+func runPreflight(command string, amiID string, region string, checkRegions []string) {
+	if skipPreflight {
+		return
+	}
+
+	log.Info("Checking the permissions in every account and region")
+
+	preflight, err := aws.NewPreflight(command, amiID, region, targetKmsKey)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	checks := preflight.Run(checkRegions)
+	failed := aws.FailedChecks(checks)
+
+	// the accounts in which the role can't be assumed are left out by the command itself
+	if skipFailedAccounts {
+		failed = slices.DeleteFunc(failed, func(check aws.Check) bool {
+			return check.Name == aws.CheckAssumeRole
+		})
+	}
+
+	if len(failed) == 0 {
+		return
+	}
+
+	_ = printChecks("table", checks)
+
+	log.Fatalf("%d of %d preflight checks failed, nothing has been changed. Use --skip-preflight to %s anyway", len(failed), len(checks), command)
+}
+
+// addPreflightFlag adds the flag to skip the preflight to a mutating command
+func addPreflightFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&skipPreflight, "skip-preflight", false, "Don't check the permissions in every account and region before changing anything")
+}
+
+func printChecks(format string, checks []aws.Check) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(checks)
+	case "table":
+		return printCheckMatrix(checks)
+	default:
+		return fmt.Errorf("unknown output format %q, expected table or json", format)
+	}
+}
+
+// printCheckMatrix prints a row per account and region with the status of every check, followed by the checks that
+// failed or were skipped
+func printCheckMatrix(checks []aws.Check) error {
+	type row struct {
+		account string
+		region  string
+	}
+
+	var rows []row
+	statuses := make(map[row]map[string]string)
+	used := make(map[string]bool)
+
+	for _, check := range checks {
+		key := row{account: check.Account, region: check.Region}
+
+		if statuses[key] == nil {
+			statuses[key] = make(map[string]string)
+			rows = append(rows, key)
+		}
+
+		statuses[key][check.Name] = check.Status
+		used[check.Name] = true
+	}
+
+	var columns []string
+	for _, column := range checkColumns {
+		if used[column] {
+			columns = append(columns, column)
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(w, "ACCOUNT\tREGION\t%s\n", strings.ToUpper(strings.Join(columns, "\t")))
+
+	for _, key := range rows {
+		region := key.region
+		if region == "" {
+			region = "-"
+		}
+
+		cells := make([]string, len(columns))
+		for i, column := range columns {
+			cells[i] = statuses[key][column]
+
+			if cells[i] == "" {
+				cells[i] = "-"
+			}
+		}
+
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", key.account, region, strings.Join(cells, "\t"))
+	}
+
+	err := w.Flush()
+
+	if err != nil {
+		return err
+	}
+
+	notPassed := append(aws.FailedChecks(checks), aws.SkippedChecks(checks)...)
+
+	if len(notPassed) == 0 {
+		return nil
+	}
+
+	fmt.Println()
+
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ACCOUNT\tREGION\tCHECK\tSTATUS\tMESSAGE")
+
+	for _, check := range notPassed {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", check.Account, check.Region, check.Name, check.Status, check.Message)
+	}
+
+	return w.Flush()
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+
+	doctorCmd.Flags().StringVar(&preflightCommand, "command", aws.PreflightCopy, "The command to check the actions of: "+strings.Join(aws.PreflightCommands, ", "))
+	doctorCmd.Flags().StringVar(&amiID, "amiID", "", "The AMI ID to check the actions of the command on, e.g. aws-0e38957fc6310ea8b")
+	doctorCmd.Flags().StringVar(&sourceRegion, "source-region", "", "The region of the AMI. When not given, the AMI is looked up in the current region and then in the other enabled regions")
+
+	doctorCmd.Flags().StringSliceVar(&regions, "regions", []string{}, "The regions to check. Can be multiple flags, or a comma-separated value")
+	_ = doctorCmd.MarkFlagRequired("regions")
+
+	doctorCmd.Flags().StringSliceVar(&accounts, "accounts", []string{}, "The account ID's to check. Can be multiple flags, or a comma-separated value")
+	addOrgFlags(doctorCmd)
+
+	doctorCmd.Flags().StringVar(&role, "role", "terraform", "The AWS IAM role to assume in the organizations, e.g. OrganizationAccountAssumeRole. Defaults to `terraform`.")
+	doctorCmd.Flags().StringVar(&targetKmsKey, "target-kms-key", "", "The KMS key for deep copies to check in every account, e.g. alias/ami")
+	doctorCmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "The output format: table or json")
+}
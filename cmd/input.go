@@ -0,0 +1,103 @@
+// Copyright © 2019 Jeroen Schepens <jeroen@cloudnatives.be>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// readAmiIDs reads AMI ID's from a file, one per line. Empty lines and lines starting with # are skipped.
+// A path of - reads from stdin.
+func readAmiIDs(path string) ([]string, error) {
+	var reader io.Reader
+
+	if path == "-" {
+		reader = os.Stdin
+	} else {
+		file, err := os.Open(path)
+
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		reader = file
+	}
+
+	var amiIDs []string
+
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		amiIDs = append(amiIDs, line)
+	}
+
+	return amiIDs, scanner.Err()
+}
+
+// parseAge parses a duration like time.ParseDuration does, but also accepts a number of days, e.g. 90d
+func parseAge(age string) (time.Duration, error) {
+	if age == "" {
+		return 0, nil
+	}
+
+	if days, found := strings.CutSuffix(age, "d"); found {
+		n, err := strconv.Atoi(days)
+
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age %q", age)
+		}
+
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	return time.ParseDuration(age)
+}
+
+// uniqueStrings removes duplicates from a slice, keeping the original order
+func uniqueStrings(slice []string) []string {
+	seen := make(map[string]bool, len(slice))
+	unique := make([]string, 0, len(slice))
+
+	for _, s := range slice {
+		if !seen[s] {
+			seen[s] = true
+			unique = append(unique, s)
+		}
+	}
+
+	return unique
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,52 @@
+// Copyright © 2019 Jeroen Schepens <jeroen@cloudnatives.be>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		age     string
+		want    time.Duration
+		wantErr bool
+	}{
+		{age: "", want: 0},
+		{age: "90d", want: 90 * 24 * time.Hour},
+		{age: "0d", want: 0},
+		{age: "36h", want: 36 * time.Hour},
+		{age: "1h30m", want: 90 * time.Minute},
+		{age: "-1d", wantErr: true},
+		{age: "d", wantErr: true},
+		{age: "1.5d", wantErr: true},
+		{age: "ninety", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.age, func(t *testing.T) {
+			got, err := parseAge(tt.age)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseAge(%q) error = %v, wantErr %v", tt.age, err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("parseAge(%q) = %v, want %v", tt.age, got, tt.want)
+			}
+		})
+	}
+}
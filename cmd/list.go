@@ -0,0 +1,152 @@
+// Copyright © 2019 Jeroen Schepens <jeroen@cloudnatives.be>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/cloudnatives/aws-ami-manager/aws"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	outputFormat string
+)
+
+// listCmd represents the list command
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists an AMI and its copies in all regions and accounts",
+	Long: `Lists an AMI and the copies made by the copy command in all regions and accounts, or the AMI's matching filters.
+
+E.g. aws-ami-manager list --amiID=ami-0e38977fc6310ea8b --regions=eu-west-1,eu-central-1 --accounts=123456789,987654321
+E.g. aws-ami-manager list --filter=tag:Env=dev --regions=eu-west-1 --output=json
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runList()
+	},
+}
+
+func runList() {
+	if amiID == "" && len(filters) == 0 {
+		log.Fatal("Either --amiID or --filter is required")
+	}
+
+	loadAWSConfigForProfiles()
+
+	listRegions := regions
+	if len(listRegions) == 0 {
+		listRegions = []string{aws.ConfigManager.GetDefaultRegion()}
+	}
+
+	var (
+		infos []aws.AmiInfo
+		err   error
+	)
+
+	if amiID != "" {
+		ami := aws.NewAmi(amiID)
+		ami.SourceRegion = aws.ConfigManager.GetDefaultRegion()
+
+		infos, err = ami.ListLineage(listRegions)
+	} else {
+		infos, err = aws.ListAmis(listRegions, filters)
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = printAmiInfos(outputFormat, infos)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func printAmiInfos(format string, infos []aws.AmiInfo) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(infos)
+	case "csv":
+		w := csv.NewWriter(os.Stdout)
+		_ = w.Write(amiInfoHeader())
+
+		for _, info := range infos {
+			_ = w.Write(amiInfoRecord(info))
+		}
+
+		w.Flush()
+
+		return w.Error()
+	case "table":
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, strings.Join(amiInfoHeader(), "\t"))
+
+		for _, info := range infos {
+			_, _ = fmt.Fprintln(w, strings.Join(amiInfoRecord(info), "\t"))
+		}
+
+		return w.Flush()
+	default:
+		return fmt.Errorf("unknown output format %q, expected table, json or csv", format)
+	}
+}
+
+func amiInfoHeader() []string {
+	return []string{"AMI ID", "NAME", "REGION", "ACCOUNT", "CREATED", "STATE", "DEPRECATED", "ENCRYPTED", "SHARED WITH", "SNAPSHOT SIZES (GiB)"}
+}
+
+func amiInfoRecord(info aws.AmiInfo) []string {
+	sizes := make([]string, len(info.SnapshotSizes))
+	for i, size := range info.SnapshotSizes {
+		sizes[i] = strconv.Itoa(int(size))
+	}
+
+	return []string{
+		info.AmiID,
+		info.Name,
+		info.Region,
+		info.Account,
+		info.CreationDate,
+		info.State,
+		info.DeprecationTime,
+		strconv.FormatBool(info.Encrypted),
+		strings.Join(info.SharedWith, ","),
+		strings.Join(sizes, ","),
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(listCmd)
+
+	listCmd.Flags().StringVar(&amiID, "amiID", "", "The source AMI ID, e.g. aws-0e38957fc6310ea8b. Lists the AMI and its copies")
+	listCmd.Flags().StringArrayVar(&filters, "filter", []string{}, "List the AMI's matching the filter, e.g. tag:Env=dev. Can be multiple flags")
+	listCmd.Flags().StringSliceVar(&regions, "regions", []string{}, "The regions to list the AMI's in. Defaults to the current region. Can be multiple flags, or a comma-separated value")
+	listCmd.Flags().StringSliceVar(&accounts, "accounts", []string{}, "The other account ID's to list the AMI's of. Can be multiple flags, or a comma-separated value")
+	addOrgFlags(listCmd)
+	listCmd.Flags().StringVar(&role, "role", "terraform", "The AWS IAM role to assume in the organizations, e.g. OrganizationAccountAssumeRole. Defaults to `terraform`.")
+	listCmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "The output format: table, json or csv")
+}
@@ -0,0 +1,73 @@
+// Copyright © 2019 Jeroen Schepens <jeroen@cloudnatives.be>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/cloudnatives/aws-ami-manager/aws"
+	"github.com/spf13/cobra"
+)
+
+var (
+	accountsFromOrg bool
+	orgUnits        []string
+	orgAccountTags  []string
+	excludeAccounts []string
+)
+
+func addOrgFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&accountsFromOrg, "accounts-from-org", false, "Add the active accounts of the organization to --accounts. Requires access to AWS Organizations")
+	cmd.Flags().StringSliceVar(&orgUnits, "org-ou", []string{}, "Only add the accounts in these OU's, given as ID or as path from the root, e.g. /Workloads/Prod. Can be multiple flags, or a comma-separated value")
+	cmd.Flags().StringSliceVar(&orgAccountTags, "org-account-tag", []string{}, "Only add the accounts with this tag, formatted as key=value. Can be multiple flags, or a comma-separated value")
+	cmd.Flags().StringSliceVar(&excludeAccounts, "exclude-accounts", []string{}, "Never add these accounts from the organization. Can be multiple flags, or a comma-separated value")
+}
+
+// loadOrgAccounts adds the accounts found in the organization to the configuration manager
+func loadOrgAccounts() error {
+	if !accountsFromOrg {
+		if len(orgUnits) > 0 || len(orgAccountTags) > 0 || len(excludeAccounts) > 0 {
+			return fmt.Errorf("--org-ou, --org-account-tag and --exclude-accounts can only be used with --accounts-from-org")
+		}
+
+		return nil
+	}
+
+	filter, err := aws.NewOrgAccountFilter(orgUnits, orgAccountTags, excludeAccounts)
+
+	if err != nil {
+		return err
+	}
+
+	orgAccounts, err := aws.ConfigManager.DiscoverAccounts(filter)
+
+	if err != nil {
+		return fmt.Errorf("unable to find the accounts in the organization: %w", err)
+	}
+
+	aws.ConfigManager.AddAccounts(orgAccounts)
+	accounts = uniqueStrings(append(accounts, orgAccounts...))
+
+	return nil
+}
+
+// requireAccounts fails when no accounts are given, neither with --accounts nor with --accounts-from-org
+func requireAccounts() error {
+	if len(accounts) == 0 && !accountsFromOrg {
+		return fmt.Errorf("--accounts or --accounts-from-org is required")
+	}
+
+	return nil
+}
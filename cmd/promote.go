@@ -0,0 +1,90 @@
+// Copyright © 2019 Jeroen Schepens <jeroen@cloudnatives.be>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/cloudnatives/aws-ami-manager/aws"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	channelName string
+	channelTag  string
+)
+
+// promoteCmd represents the promote command
+var promoteCmd = &cobra.Command{
+	Use:   "promote",
+	Short: "Promotes an AMI and its copies to a release channel",
+	Long: `Promotes an AMI and its copies to a release channel, e.g. candidate, staging or production.
+
+The channel tag is moved from the AMI's that held the channel to the AMI and its copies, in every region and account.
+The AMI that held the channel before is kept in the ami-manager:<channel>:previous tag, so the promotion can be rolled
+back. When one of the changes fails, the changes that were already made are undone.
+
+Before anything is changed, the permissions are checked in every account and region with DryRun requests, see the doctor
+command. Use --skip-preflight to skip these checks.
+
+E.g. aws-ami-manager promote --amiID=ami-0e38977fc6310ea8b --channel=production --regions=eu-west-1,eu-central-1 --accounts=123456789,987654321
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runPromote()
+	},
+}
+
+func runPromote() {
+	loadAWSConfigForProfiles()
+
+	ami := aws.NewAmi(amiID)
+	ami.SourceRegion = aws.ConfigManager.GetDefaultRegion()
+
+	channel := aws.NewChannel(channelName, channelTag)
+
+	runPreflight(aws.PreflightPromote, ami.SourceAmiID, ami.SourceRegion, regions)
+
+	err := channel.Promote(ami, regions)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Infof("AMI %s has been promoted to %s", ami.SourceAmiID, channelName)
+}
+
+// addChannelFlags adds the flags shared by the promote and rollback commands
+func addChannelFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&channelName, "channel", "", "The release channel, e.g. production")
+	_ = cmd.MarkFlagRequired("channel")
+
+	cmd.Flags().StringVar(&channelTag, "channel-tag", aws.DefaultChannelTag, "The tag that marks the release channel")
+
+	cmd.Flags().StringSliceVar(&regions, "regions", []string{}, "The regions the AMI has been copied to. Can be multiple flags, or a comma-separated value")
+	_ = cmd.MarkFlagRequired("regions")
+
+	cmd.Flags().StringSliceVar(&accounts, "accounts", []string{}, "The account ID's the AMI has been shared with. Can be multiple flags, or a comma-separated value")
+	addOrgFlags(cmd)
+	cmd.Flags().StringVar(&role, "role", "terraform", "The AWS IAM role to assume in the organizations, e.g. OrganizationAccountAssumeRole. Defaults to `terraform`.")
+}
+
+func init() {
+	rootCmd.AddCommand(promoteCmd)
+
+	promoteCmd.Flags().StringVar(&amiID, "amiID", "", "The source AMI ID, e.g. aws-0e38957fc6310ea8b")
+	_ = promoteCmd.MarkFlagRequired("amiID")
+
+	addChannelFlags(promoteCmd)
+	addPreflightFlag(promoteCmd)
+}
@@ -0,0 +1,76 @@
+// Copyright © 2019 Jeroen Schepens <jeroen@cloudnatives.be>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/cloudnatives/aws-ami-manager/aws"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	assumeYes     bool
+	force         bool
+	protectionTag string
+)
+
+// addProtectionFlags adds the flags that guard the destructive commands
+func addProtectionFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Don't ask for confirmation before removing AMI's")
+	cmd.Flags().BoolVar(&force, "force", false, "Also remove AMI's with the protection tag or with deregistration protection")
+	cmd.Flags().StringVar(&protectionTag, "protection-tag", aws.DefaultProtectionTag, "AMI's with this tag are never removed, unless forced. Formatted as key=value, leave empty to disable")
+}
+
+func loadProtectionPolicy() {
+	policy, err := aws.NewProtectionPolicy(protectionTag, force)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	aws.Protection = policy
+}
+
+// confirm prints the tab-separated lines describing what is about to happen and asks the user to continue,
+// unless --yes was given.
+func confirm(question string, lines []string) bool {
+	if assumeYes {
+		return true
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, line := range lines {
+		_, _ = fmt.Fprintln(w, line)
+	}
+	_ = w.Flush()
+
+	fmt.Printf("%s [y/N]: ", question)
+
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+
+	if err != nil && answer == "" {
+		return false
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+
+	return answer == "y" || answer == "yes"
+}
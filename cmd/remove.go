@@ -15,18 +15,54 @@
 package cmd
 
 import (
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
 	"github.com/cloudnatives/aws-ami-manager/aws"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
+var (
+	allCopies   bool
+	amiIDs      []string
+	amiIDsFile  string
+	filters     []string
+	olderThan   string
+	concurrency int
+)
+
 // removeCmd represents the remove command
 var removeCmd = &cobra.Command{
 	Use:   "remove",
-	Short: "Removes an AMI in your current region",
-	Long: `Removes an AMI in your current region.
+	Short: "Removes AMI's",
+	Long: `Removes AMI's in the region given with --source-region, or --region. When no region is given, every AMI is looked up in the
+current region first, and then in the other enabled regions.
+
+The AMI's to remove are given with --amiID, read from a file with --from-file (use - for stdin), or selected with
+--filter and --older-than. The AMI's are removed concurrently and a summary is printed afterwards.
 
 E.g. ./aws-ami-manager remove --amiID=ami-075d87a3d4512bee5
+E.g. ./aws-ami-manager remove --source-region=eu-west-1 --filter=tag:Env=dev --older-than=90d
+
+With --all-copies, the copies made by the copy command are removed from the given regions as well, including the deep
+copies in the given accounts. Launch permissions are revoked and the tags are removed from the given accounts before the
+AMI's and their snapshots are deleted.
+
+E.g. ./aws-ami-manager remove --amiID=ami-075d87a3d4512bee5 --all-copies --regions=eu-west-1,eu-central-1 --accounts=123456789,987654321
+
+When the AMI's are shared with this account, --source-account gives the account that owns them. Only their copies are
+removed then, including the copies in the region of the AMI's.
+
+The AMI's are listed and have to be confirmed before they are removed, unless --yes is given. AMI's with the protection
+tag or with deregistration protection are never removed, unless --force is given.
+
+Before anything is removed, the permissions are checked in every account and region with DryRun requests, see the doctor
+command. Use --skip-preflight to skip these checks.
 `,
 	Run: func(cmd *cobra.Command, args []string) {
 		runRemove()
@@ -34,26 +70,228 @@ E.g. ./aws-ami-manager remove --amiID=ami-075d87a3d4512bee5
 }
 
 func runRemove() {
-	cm := aws.NewConfigurationManager()
+	if allCopies && len(regions) == 0 {
+		log.Fatal("--regions is required when removing all copies")
+	}
 
-	ami := aws.NewAmi(amiID)
-	ami.SourceRegion = cm.GetDefaultRegion()
+	if amiIDsFile == "-" && !assumeYes {
+		log.Fatal("--yes is required when reading AMI ID's from stdin")
+	}
+
+	// a shared AMI can't be removed by this account, only its copies
+	if sourceAccount != "" && !allCopies {
+		log.Fatal("--source-account can only be used with --all-copies")
+	}
 
-	aws.ConfigManager = cm
+	loadAWSConfigForProfiles()
+	addSourceAccount()
+	loadProtectionPolicy()
 
-	err := ami.RemoveAmi()
+	idsPerRegion, err := selectAmiIDs()
 
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	log.Infof("AMI %s has been removed successfully", ami.SourceAmiID)
+	if len(idsPerRegion) == 0 {
+		log.Info("No AMI's to remove")
+		return
+	}
+
+	var (
+		lines []string
+		total int
+	)
+
+	for _, region := range sortedRegions(idsPerRegion) {
+		described, missing, err := aws.DescribeAmis(idsPerRegion[region], region)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		for _, id := range missing {
+			log.Warnf("AMI %s not found in region %s, skipping", id, region)
+		}
+
+		idsPerRegion[region] = withoutStrings(idsPerRegion[region], missing)
+		if len(idsPerRegion[region]) == 0 {
+			delete(idsPerRegion, region)
+			continue
+		}
+
+		lines = append(lines, described...)
+		total += len(idsPerRegion[region])
+
+		if allCopies {
+			copies, err := aws.DescribeCopies(idsPerRegion[region], region, sourceAccount, regions)
+
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			lines = append(lines, copies...)
+		}
+	}
+
+	if total == 0 {
+		log.Info("No AMI's to remove")
+		return
+	}
+
+	// the actions are checked against the first AMI of every region, the others need the same permissions
+	for _, region := range sortedRegions(idsPerRegion) {
+		runPreflight(aws.PreflightRemove, idsPerRegion[region][0], region, uniqueStrings(append([]string{region}, regions...)))
+	}
+
+	question := fmt.Sprintf("Remove these %d AMI's?", total)
+	if allCopies {
+		question = fmt.Sprintf("Remove these %d AMI's and their copies in %s?", total, strings.Join(regions, ", "))
+	}
+	if sourceAccount != "" {
+		question = fmt.Sprintf("Remove the copies of these %d AMI's in %s?", total, strings.Join(regions, ", "))
+	}
+
+	if !confirm(question, lines) {
+		log.Info("Nothing has been removed")
+		return
+	}
+
+	var results []aws.RemoveResult
+	for _, region := range sortedRegions(idsPerRegion) {
+		results = append(results, aws.RemoveAmis(idsPerRegion[region], region, sourceAccount, concurrency, allCopies, regions)...)
+	}
+
+	failed := printRemoveResults(results)
+
+	if failed > 0 {
+		log.Fatalf("%d of %d AMI's could not be removed", failed, len(results))
+	}
+
+	log.Infof("%d AMI's have been removed successfully", len(results))
+}
+
+// selectAmiIDs combines the AMI ID's given as flags, read from a file and selected by filters, grouped by region
+func selectAmiIDs() (map[string][]string, error) {
+	ids := append([]string{}, amiIDs...)
+
+	if amiIDsFile != "" {
+		fromFile, err := readAmiIDs(amiIDsFile)
+
+		if err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, fromFile...)
+	}
+
+	if len(ids) == 0 && amiIDsFile == "" && len(filters) == 0 && olderThan == "" {
+		return nil, fmt.Errorf("no AMI's given, use --amiID, --from-file, --filter or --older-than")
+	}
+
+	idsPerRegion := make(map[string][]string)
+
+	for _, id := range uniqueStrings(ids) {
+		region, err := resolveSourceRegion(id)
+
+		if err != nil {
+			return nil, err
+		}
+
+		idsPerRegion[region] = append(idsPerRegion[region], id)
+	}
+
+	if len(filters) > 0 || olderThan != "" {
+		age, err := parseAge(olderThan)
+
+		if err != nil {
+			return nil, err
+		}
+
+		region := sourceRegion
+		if region == "" {
+			region = aws.ConfigManager.GetDefaultRegion()
+		}
+
+		matched, err := aws.FindAmiIDs(region, filters, age)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if len(matched) > 0 {
+			idsPerRegion[region] = uniqueStrings(append(idsPerRegion[region], matched...))
+		}
+	}
+
+	return idsPerRegion, nil
+}
+
+// withoutStrings returns the values of slice that are not in remove, keeping the original order
+func withoutStrings(slice []string, remove []string) []string {
+	kept := make([]string, 0, len(slice))
+
+	for _, s := range slice {
+		if !slices.Contains(remove, s) {
+			kept = append(kept, s)
+		}
+	}
+
+	return kept
+}
+
+func sortedRegions(idsPerRegion map[string][]string) []string {
+	sorted := make([]string, 0, len(idsPerRegion))
+
+	for region := range idsPerRegion {
+		sorted = append(sorted, region)
+	}
+
+	sort.Strings(sorted)
+
+	return sorted
+}
+
+func printRemoveResults(results []aws.RemoveResult) int {
+	failed := 0
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "AMI ID\tREGION\tRESULT")
+
+	for _, result := range results {
+		status := "removed"
+		if result.Err != nil {
+			status = "failed: " + result.Err.Error()
+			failed++
+		}
+
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", result.AmiID, result.Region, status)
+	}
+
+	_ = w.Flush()
+
+	return failed
 }
 
 func init() {
 	rootCmd.AddCommand(removeCmd)
 
-	removeCmd.Flags().StringVar(&amiID, "amiID", "", "The source AMI ID, e.g. aws-0e38957fc6310ea8b")
-	_ = removeCmd.MarkFlagRequired("amiID")
+	removeCmd.Flags().StringSliceVar(&amiIDs, "amiID", []string{}, "The AMI ID's to remove, e.g. aws-0e38957fc6310ea8b. Can be multiple flags, or a comma-separated value")
+	removeCmd.Flags().StringVar(&amiIDsFile, "from-file", "", "A file with the AMI ID's to remove, one per line. Use - to read from stdin")
+	removeCmd.Flags().StringVar(&sourceRegion, "source-region", "", "The region to remove the AMI's from. When not given, every AMI is looked up in the current region and then in the other enabled regions")
+	removeCmd.Flags().StringVar(&sourceRegion, "region", "", "Same as --source-region")
+	removeCmd.Flags().StringArrayVar(&filters, "filter", []string{}, "Remove the AMI's owned by this account matching the filter, e.g. tag:Env=dev. Can be multiple flags")
+	removeCmd.Flags().StringVar(&olderThan, "older-than", "", "Only remove AMI's older than this age, e.g. 90d or 12h")
+	removeCmd.Flags().IntVar(&concurrency, "concurrency", 5, "The number of AMI's that are removed at the same time")
+
+	removeCmd.Flags().BoolVar(&allCopies, "all-copies", false, "Also remove the copies of the AMI in the given regions and accounts")
+	removeCmd.Flags().StringSliceVar(&regions, "regions", []string{}, "The regions to remove the copies from. Can be multiple flags, or a comma-separated value")
+	removeCmd.Flags().StringSliceVar(&accounts, "accounts", []string{}, "The account ID's the AMI has been shared with. Can be multiple flags, or a comma-separated value")
+	addOrgFlags(removeCmd)
+	removeCmd.Flags().StringVar(&sourceAccount, "source-account", "", "The account that owns the AMI's, when they are shared with this account. Requires --all-copies")
+	removeCmd.Flags().StringVar(&sourceRole, "source-role", "", "The AWS IAM role to assume in the source account. Defaults to --role")
+	removeCmd.Flags().StringVar(&role, "role", "terraform", "The AWS IAM role to assume in the organizations, e.g. OrganizationAccountAssumeRole. Defaults to `terraform`.")
 
+	addProtectionFlags(removeCmd)
+	addPreflightFlag(removeCmd)
 }
@@ -0,0 +1,54 @@
+// Copyright © 2019 Jeroen Schepens <jeroen@cloudnatives.be>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/cloudnatives/aws-ami-manager/aws"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// rollbackCmd represents the rollback command
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback",
+	Short: "Moves a release channel back to the AMI's that held it before",
+	Long: `Moves a release channel back to the AMI's that held it before the last promotion, in every region and account.
+
+E.g. aws-ami-manager rollback --channel=production --regions=eu-west-1,eu-central-1 --accounts=123456789,987654321
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runRollback()
+	},
+}
+
+func runRollback() {
+	loadAWSConfigForProfiles()
+
+	channel := aws.NewChannel(channelName, channelTag)
+
+	err := channel.Rollback(regions)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Infof("Channel %s has been rolled back", channelName)
+}
+
+func init() {
+	rootCmd.AddCommand(rollbackCmd)
+
+	addChannelFlags(rollbackCmd)
+}
@@ -17,6 +17,7 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/cloudnatives/aws-ami-manager/aws"
 	"github.com/sirupsen/logrus"
@@ -29,8 +30,18 @@ var (
 	amiID    string
 	regions  []string
 	role     string
+
+	sourceRegion string
+
+	accountsConfigFile string
+	skipFailedAccounts bool
+	stsCredentialsFile string
+	endpointOverrides  []string
 )
 
+// exitCodePartialFailure is the exit code when the command succeeded, but not in every account
+const exitCodePartialFailure = 2
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "aws-aws-manager",
@@ -52,8 +63,24 @@ func Execute() {
 		fmt.Println(err)
 		os.Exit(1)
 	}
+
+	if aws.ConfigManager != nil && len(aws.ConfigManager.FailedAccounts()) > 0 {
+		logrus.Errorf("Partial failure, the role could not be assumed in accounts %s", strings.Join(aws.ConfigManager.FailedAccounts(), ", "))
+		os.Exit(exitCodePartialFailure)
+	}
 }
 
 func init() {
+	// the default of --role only applies when the accounts config doesn't give a role either
+	rootCmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		if flag := cmd.Flags().Lookup("role"); flag != nil && !flag.Changed {
+			role = ""
+		}
+	}
+
 	rootCmd.PersistentFlags().StringVar(&logLevel, "loglevel", logrus.DebugLevel.String(), "Set the log level")
+	rootCmd.PersistentFlags().BoolVar(&skipFailedAccounts, "skip-failed-accounts", false, "Continue with the other accounts when the role can't be assumed in an account, and exit with code 2 afterwards")
+	rootCmd.PersistentFlags().StringVar(&stsCredentialsFile, "sts-credentials", "", "A JSON file with STS credentials to use instead of the default credentials, e.g. the output of aws sts assume-role-with-web-identity. Defaults to $"+aws.StsCredentialsEnvVar)
+	rootCmd.PersistentFlags().StringArrayVar(&endpointOverrides, "endpoint", []string{}, "Override the endpoint of ec2 or sts, formatted as service=url for every region or service:region=url, e.g. ec2=http://localhost:4566. Can be multiple flags")
+	rootCmd.PersistentFlags().StringVar(&accountsConfigFile, "accounts-config", "", "A JSON file with the role ARN, external ID, session name, duration and partition per account")
 }
@@ -0,0 +1,96 @@
+// Copyright © 2019 Jeroen Schepens <jeroen@cloudnatives.be>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/cloudnatives/aws-ami-manager/aws"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	prune bool
+)
+
+// syncCmd represents the sync command
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Reconciles the launch permissions and tags of an AMI and its copies",
+	Long: `Reconciles the launch permissions and tags of an AMI and its existing copies, without copying the AMI again.
+
+Missing launch permissions are added to the copies for the accounts and the tags of the source AMI are set in every
+account. Like copy, the source AMI itself is never shared. Use it to fix drift, or to onboard a new account. With
+--prune, launch permissions of other accounts and tags that are no longer on the source AMI are removed from the copies.
+
+The tags are transformed like copy did, so give the same --add-tag, --drop-tag and --rename-tag flags. The provenance
+and lineage tags are never removed.
+
+Before anything is changed, the permissions are checked in every account and region with DryRun requests, see the doctor
+command. Use --skip-preflight to skip these checks.
+
+E.g. aws-ami-manager sync --amiID=ami-0e38977fc6310ea8b --regions=eu-west-1,eu-central-1 --accounts=123456789,987654321
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runSync()
+	},
+}
+
+func runSync() {
+	err := requireAccounts()
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	tagRules, err := aws.NewTagRules(addTags, dropTags, renameTags, false)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	loadAWSConfigForProfiles()
+
+	ami := aws.NewAmi(amiID)
+	ami.SourceRegion = aws.ConfigManager.GetDefaultRegion()
+	ami.TagRules = tagRules
+
+	runPreflight(aws.PreflightSync, ami.SourceAmiID, ami.SourceRegion, regions)
+
+	err = ami.Sync(regions, prune)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Infof("AMI %s and its copies have been synced successfully", ami.SourceAmiID)
+}
+
+func init() {
+	rootCmd.AddCommand(syncCmd)
+
+	syncCmd.Flags().StringVar(&amiID, "amiID", "", "The source AMI ID, e.g. aws-0e38957fc6310ea8b")
+	_ = syncCmd.MarkFlagRequired("amiID")
+
+	syncCmd.Flags().StringSliceVar(&regions, "regions", []string{}, "The regions the AMI has been copied to. Can be multiple flags, or a comma-separated value")
+	_ = syncCmd.MarkFlagRequired("regions")
+
+	syncCmd.Flags().StringSliceVar(&accounts, "accounts", []string{}, "The account ID's that will be authorized to use the Ami's. Can be multiple flags, or a comma-separated value")
+	addOrgFlags(syncCmd)
+	addTagRuleFlags(syncCmd)
+
+	syncCmd.Flags().StringVar(&role, "role", "terraform", "The AWS IAM role to assume in the organizations, e.g. OrganizationAccountAssumeRole. Defaults to `terraform`.")
+	syncCmd.Flags().BoolVar(&prune, "prune", false, "Remove launch permissions of other accounts and tags that copy would not set")
+	addPreflightFlag(syncCmd)
+}
@@ -0,0 +1,88 @@
+// Copyright © 2019 Jeroen Schepens <jeroen@cloudnatives.be>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/cloudnatives/aws-ami-manager/aws"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	organizations       []string
+	organizationalUnits []string
+	removeTags          bool
+)
+
+// unshareCmd represents the unshare command
+var unshareCmd = &cobra.Command{
+	Use:   "unshare",
+	Short: "Takes an AMI and its copies away from accounts, organizations or organizational units",
+	Long: `Takes an AMI and its copies away from accounts, organizations or organizational units.
+
+The launch permissions are removed from the AMI and its copies in every region, as well as the create volume permissions
+of their snapshots. With --remove-tags, the tags set by the copy command are removed from the accounts first.
+
+Before anything is changed, the permissions are checked in every account and region with DryRun requests, see the doctor
+command. Use --skip-preflight to skip these checks.
+
+E.g. aws-ami-manager unshare --amiID=ami-0e38977fc6310ea8b --regions=eu-west-1,eu-central-1 --accounts=123456789 --remove-tags
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runUnshare()
+	},
+}
+
+func runUnshare() {
+	if len(accounts) == 0 && !accountsFromOrg && len(organizations) == 0 && len(organizationalUnits) == 0 {
+		log.Fatal("At least one of --accounts, --accounts-from-org, --organizations or --organizational-units is required")
+	}
+
+	loadAWSConfigForProfiles()
+
+	// the accounts found in the organization are added to accounts when the configuration is loaded
+	principals := append(append(append([]string{}, accounts...), organizations...), organizationalUnits...)
+
+	ami := aws.NewAmi(amiID)
+	ami.SourceRegion = aws.ConfigManager.GetDefaultRegion()
+
+	runPreflight(aws.PreflightUnshare, ami.SourceAmiID, ami.SourceRegion, regions)
+
+	err := ami.Unshare(regions, principals, removeTags)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Infof("AMI %s and its copies have been unshared successfully", ami.SourceAmiID)
+}
+
+func init() {
+	rootCmd.AddCommand(unshareCmd)
+
+	unshareCmd.Flags().StringVar(&amiID, "amiID", "", "The source AMI ID, e.g. aws-0e38957fc6310ea8b")
+	_ = unshareCmd.MarkFlagRequired("amiID")
+
+	unshareCmd.Flags().StringSliceVar(&regions, "regions", []string{}, "The regions the AMI has been copied to. Can be multiple flags, or a comma-separated value")
+	_ = unshareCmd.MarkFlagRequired("regions")
+
+	unshareCmd.Flags().StringSliceVar(&accounts, "accounts", []string{}, "The account ID's to take the AMI's away from. Can be multiple flags, or a comma-separated value")
+	addOrgFlags(unshareCmd)
+	unshareCmd.Flags().StringSliceVar(&organizations, "organizations", []string{}, "The organization ARN's to take the AMI's away from. Can be multiple flags, or a comma-separated value")
+	unshareCmd.Flags().StringSliceVar(&organizationalUnits, "organizational-units", []string{}, "The organizational unit ARN's to take the AMI's away from. Can be multiple flags, or a comma-separated value")
+	unshareCmd.Flags().BoolVar(&removeTags, "remove-tags", false, "Also remove the tags of the source AMI from the accounts")
+	unshareCmd.Flags().StringVar(&role, "role", "terraform", "The AWS IAM role to assume in the organizations, e.g. OrganizationAccountAssumeRole. Defaults to `terraform`.")
+	addPreflightFlag(unshareCmd)
+}
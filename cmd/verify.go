@@ -0,0 +1,127 @@
+// Copyright © 2019 Jeroen Schepens <jeroen@cloudnatives.be>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/cloudnatives/aws-ami-manager/aws"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// verifyCmd represents the verify command
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verifies that the copies of an AMI are in line with the source AMI",
+	Long: `Verifies that the copies of an AMI are in line with the source AMI.
+
+The name, tags, launch permissions, encryption and boot mode of every copy are compared with the source AMI, as well as
+the tags in every account. The command exits with a non-zero exit code when differences are found.
+
+The tags are expected to be transformed like copy did, so give the same --add-tag, --drop-tag and --rename-tag flags.
+The provenance and lineage tags are not compared. Likewise, give the --name-template the copies were named with. The
+names are rendered with the date of every copy and the git SHA of the environment.
+
+E.g. aws-ami-manager verify --amiID=ami-0e38977fc6310ea8b --regions=eu-west-1,eu-central-1 --accounts=123456789,987654321
+	`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runVerify()
+	},
+}
+
+func runVerify() {
+	tagRules, err := aws.NewTagRules(addTags, dropTags, renameTags, false)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	naming, err := aws.NewNaming(nameTemplate, "")
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	loadAWSConfigForProfiles()
+
+	ami := aws.NewAmi(amiID)
+	ami.SourceRegion = aws.ConfigManager.GetDefaultRegion()
+	ami.TagRules = tagRules
+	ami.Naming = naming
+
+	drifts, err := ami.Verify(regions)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = printDrifts(outputFormat, drifts)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if len(drifts) > 0 {
+		log.Fatalf("Found %d differences between AMI %s and its copies", len(drifts), ami.SourceAmiID)
+	}
+
+	log.Infof("All copies of AMI %s are in line", ami.SourceAmiID)
+}
+
+func printDrifts(format string, drifts []aws.Drift) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(drifts)
+	case "table":
+		if len(drifts) == 0 {
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "REGION\tACCOUNT\tAMI ID\tFIELD\tEXPECTED\tACTUAL")
+
+		for _, drift := range drifts {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", drift.Region, drift.Account, drift.AmiID, drift.Field, drift.Expected, drift.Actual)
+		}
+
+		return w.Flush()
+	default:
+		return fmt.Errorf("unknown output format %q, expected table or json", format)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+
+	verifyCmd.Flags().StringVar(&amiID, "amiID", "", "The source AMI ID, e.g. aws-0e38957fc6310ea8b")
+	_ = verifyCmd.MarkFlagRequired("amiID")
+
+	verifyCmd.Flags().StringSliceVar(&regions, "regions", []string{}, "The regions the AMI has been copied to. Can be multiple flags, or a comma-separated value")
+	_ = verifyCmd.MarkFlagRequired("regions")
+
+	verifyCmd.Flags().StringSliceVar(&accounts, "accounts", []string{}, "The account ID's the AMI has been shared with. Can be multiple flags, or a comma-separated value")
+	addOrgFlags(verifyCmd)
+	addTagRuleFlags(verifyCmd)
+	verifyCmd.Flags().StringVar(&nameTemplate, "name-template", "", "The Go template the names of the copies were rendered with by copy. Defaults to the name of the source AMI")
+	verifyCmd.Flags().StringVar(&role, "role", "terraform", "The AWS IAM role to assume in the organizations, e.g. OrganizationAccountAssumeRole. Defaults to `terraform`.")
+	verifyCmd.Flags().StringVarP(&outputFormat, "output", "o", "table", "The output format: table or json")
+}